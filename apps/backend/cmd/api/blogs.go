@@ -12,7 +12,7 @@ import (
 )
 
 type BlogsHandler struct {
-	repo *blogs.Repository
+	repo blogs.Store
 }
 
 func renderBlogCards(items []blogs.BlogInfo) (string, error) {
@@ -23,9 +23,9 @@ func renderBlogCards(items []blogs.BlogInfo) (string, error) {
 	return buffer.String(), nil
 }
 
-func renderBlogFeed(items []blogs.BlogInfo) (string, error) {
+func renderBlogFeed(feed blogs.BlogFeed) (string, error) {
 	var buffer bytes.Buffer
-	if err := blogs.BlogFeedTemplate.Execute(&buffer, items); err != nil {
+	if err := blogs.BlogFeedTemplate.Execute(&buffer, feed); err != nil {
 		return "", fmt.Errorf("error parsing blog entries: %w", err)
 	}
 	return buffer.String(), nil
@@ -49,8 +49,8 @@ func encodeBlogsHTML(w http.ResponseWriter, items []blogs.BlogInfo) {
 	io.WriteString(w, content)
 }
 
-func encodeBlogsRSS(w http.ResponseWriter, items []blogs.BlogInfo) {
-	content, err := renderBlogFeed(items)
+func encodeBlogsRSS(w http.ResponseWriter, feed blogs.BlogFeed) {
+	content, err := renderBlogFeed(feed)
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -59,7 +59,7 @@ func encodeBlogsRSS(w http.ResponseWriter, items []blogs.BlogInfo) {
 	io.WriteString(w, content)
 }
 
-func NewBlogsHandler(repo *blogs.Repository) *BlogsHandler {
+func NewBlogsHandler(repo blogs.Store) *BlogsHandler {
 	return &BlogsHandler{repo: repo}
 }
 
@@ -78,9 +78,9 @@ func (h *BlogsHandler) Read(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Collection not found", http.StatusNotFound)
 			return
 		}
-		items, err = h.repo.GetBlogsByKind(kind)
+		items, err = h.repo.GetBlogsByKind(r.Context(), kind)
 	} else {
-		items, err = h.repo.GetAllBlogs()
+		items, err = h.repo.GetAllBlogs(r.Context())
 	}
 
 	if err != nil {
@@ -88,6 +88,11 @@ func (h *BlogsHandler) Read(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	items, ok := filterBlogs(w, r, items)
+	if !ok {
+		return
+	}
+
 	accept := r.Header.Get("Accept")
 
 	if strings.Contains(accept, "application/json") {
@@ -96,7 +101,7 @@ func (h *BlogsHandler) Read(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if strings.Contains(accept, "application/rss+xml") {
-		encodeBlogsRSS(w, items)
+		encodeBlogsRSS(w, blogs.BlogFeed{Blogs: items})
 		return
 	}
 
@@ -110,11 +115,42 @@ func (h *BlogsHandler) Read(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *BlogsHandler) RSS(w http.ResponseWriter, r *http.Request) {
-	items, err := h.repo.GetAllBlogs()
+	items, err := h.repo.GetAllBlogs(r.Context())
 	if err != nil {
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	encodeBlogsRSS(w, items)
+	items, ok := filterBlogs(w, r, items)
+	if !ok {
+		return
+	}
+
+	encodeBlogsRSS(w, blogs.BlogFeed{Blogs: items})
+}
+
+// filterBlogs applies the query filters: the active and dormant blogs are
+// kept, along with the lifecycle states listed in ?include= (e.g. "dead" or
+// "all"), and only the ones written in ?lang= or tagged with ?tag= when
+// given. It answers with an error and returns false when a parameter is
+// invalid.
+func filterBlogs(w http.ResponseWriter, r *http.Request, items []blogs.BlogInfo) ([]blogs.BlogInfo, bool) {
+	query := r.URL.Query()
+
+	lifecycles, err := blogs.ParseLifecycles(query.Get("include"))
+	if err != nil {
+		http.Error(w, "Invalid include parameter", http.StatusBadRequest)
+		return nil, false
+	}
+	items = blogs.FilterByLifecycle(items, lifecycles...)
+
+	if lang := strings.TrimSpace(query.Get("lang")); lang != "" {
+		items = blogs.FilterByLang(items, lang)
+	}
+
+	if tag := strings.ToLower(strings.TrimSpace(query.Get("tag"))); tag != "" {
+		items = blogs.FilterByTag(items, tag)
+	}
+
+	return items, true
 }
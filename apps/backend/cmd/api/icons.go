@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+type IconsHandler struct {
+	repo blogs.Store
+}
+
+func NewIconsHandler(repo blogs.Store) *IconsHandler {
+	return &IconsHandler{repo: repo}
+}
+
+// Read serves the cached icon of a blog. Icon URLs carry a version of their
+// content, so requests naming the current version can be cached for good.
+func (h *IconsHandler) Read(w http.ResponseWriter, r *http.Request) {
+	icon, err := h.repo.GetBlogIcon(r.Context(), r.PathValue("blog"))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if icon == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	etag := `"` + icon.ContentHash + `"`
+	w.Header().Set("ETag", etag)
+	if version := r.URL.Query().Get("v"); version != "" && version == blogs.IconVersion(icon.ContentHash) {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+	}
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write(icon.Content)
+}
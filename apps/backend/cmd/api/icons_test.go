@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+type iconStore struct {
+	blogs.Store
+	icon *blogs.BlogIcon
+}
+
+func (s iconStore) GetBlogIcon(ctx context.Context, blogName string) (*blogs.BlogIcon, error) {
+	return s.icon, nil
+}
+
+func TestIconsHandlerCacheControl(t *testing.T) {
+	hash := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	handler := NewIconsHandler(iconStore{icon: &blogs.BlogIcon{
+		BlogName:    "Alpha",
+		Content:     []byte("png"),
+		ContentHash: hash,
+	}})
+
+	tests := []struct {
+		name     string
+		version  string
+		expected string
+	}{
+		{"current version", "0123456789abcdef", "public, max-age=31536000, immutable"},
+		{"no version", "", "public, max-age=86400"},
+		{"short prefix", "0", "public, max-age=86400"},
+		{"full hash", hash, "public, max-age=86400"},
+		{"stale version", "fedcba9876543210", "public, max-age=86400"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/blogs/Alpha/icon?v="+tt.version, nil)
+			req.SetPathValue("blog", "Alpha")
+			rec := httptest.NewRecorder()
+
+			handler.Read(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if got := rec.Header().Get("Cache-Control"); got != tt.expected {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+type LinksHandler struct {
+	repo blogs.Store
+}
+
+func NewLinksHandler(repo blogs.Store) *LinksHandler {
+	return &LinksHandler{repo: repo}
+}
+
+// Read lists the latest link checks, or only the broken links with
+// ?broken=true.
+func (h *LinksHandler) Read(w http.ResponseWriter, r *http.Request) {
+	brokenOnly := false
+	if param := r.URL.Query().Get("broken"); param != "" {
+		var err error
+		brokenOnly, err = strconv.ParseBool(param)
+		if err != nil {
+			http.Error(w, "Invalid broken parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	checks, err := h.repo.GetLinkChecks(r.Context(), brokenOnly)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if checks == nil {
+		checks = []blogs.LinkCheck{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(checks); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -1,16 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/nesco/techblogs/backend/internal/database"
+	"github.com/nesco/techblogs/backend/internal/blogs"
 	"go.uber.org/zap"
 )
 
@@ -49,6 +50,22 @@ func getListener(addr string) (net.Listener, error) {
 	return ln, nil
 }
 
+// openDatabase opens the database configured by the environment, see
+// blogs.OpenFromEnv. readOnly only applies to SQLite.
+func openDatabase(sugar *zap.SugaredLogger, readOnly bool) *blogs.Database {
+	opened, err := blogs.OpenFromEnv(readOnly)
+	if err != nil {
+		sugar.Fatalw("Failed to open database", "error", err)
+	}
+	if opened.Driver == "sqlite" {
+		sugar.Infow("Database initialized", "driver", opened.Driver, "path", opened.Path,
+			"journal_mode", opened.SQLiteOptions.JournalMode, "read_only", opened.SQLiteOptions.ReadOnly)
+	} else {
+		sugar.Infow("Database initialized", "driver", opened.Driver)
+	}
+	return opened
+}
+
 func main() {
 	startTime := time.Now()
 
@@ -59,25 +76,58 @@ func main() {
 	defer logger.Sync()
 
 	sugar := logger.Sugar()
-	sugar.Infow("Techblogs backend started")
 
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "./data/techblogs.db"
+	// Commands only log warnings and errors, their output being for humans
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			runMigrate(logger.WithOptions(zap.IncreaseLevel(zap.WarnLevel)).Sugar(), os.Args[2:])
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown command %q (expected: migrate)\n", os.Args[1])
+			os.Exit(2)
+		}
+		return
 	}
 
-	db, err := database.InitDB(dbPath)
-	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	sugar.Infow("Techblogs backend started")
+
+	autoMigrate := false
+	if value := os.Getenv("AUTO_MIGRATE"); value != "" {
+		autoMigrate, err = strconv.ParseBool(value)
+		if err != nil {
+			sugar.Fatalw("Invalid AUTO_MIGRATE: expected true or false", "value", value)
+		}
+	}
+	// The API only reads, so it can keep away from the scraper's writes
+	readOnly := false
+	if value := os.Getenv("DB_READ_ONLY"); value != "" {
+		readOnly, err = strconv.ParseBool(value)
+		if err != nil {
+			sugar.Fatalw("Invalid DB_READ_ONLY: expected true or false", "value", value)
+		}
+	}
+	if autoMigrate && readOnly {
+		sugar.Fatal("AUTO_MIGRATE needs a writable database: unset DB_READ_ONLY")
 	}
-	defer db.Close()
 
-	sugar.Infow("Database initialized", "path", dbPath)
+	opened := openDatabase(sugar, readOnly)
+	defer opened.Close()
+
+	if autoMigrate {
+		applied, err := opened.Migrator.Up(context.Background())
+		if err != nil {
+			sugar.Fatalw("Failed to migrate database", "error", err)
+		}
+		sugar.Infow("Database migrated", "applied", len(applied), "version", opened.Migrator.Latest())
+	}
+	if err := opened.CheckVersion(context.Background()); err != nil {
+		sugar.Fatalw("Database schema is out of date", "error", err)
+	}
 
 	// Setting-up the HTTP Server
 	mux := http.NewServeMux()
 
-	registerRoutes(mux, startTime, db, sugar)
+	registerRoutes(mux, startTime, opened.Repo, sugar)
 
 	addr := os.Getenv("LISTEN_ADDR")
 	ln, err := getListener(addr)
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"go.uber.org/zap"
+)
+
+// migrateCommand describes a migrate subcommand and its arguments.
+type migrateCommand struct {
+	name, args, usage string
+}
+
+var migrateCommands = []migrateCommand{
+	{"up", "", "apply the pending migrations"},
+	{"down", "[N]", "revert the latest migration, or the latest N"},
+	{"status", "", "list the migrations and whether they are applied"},
+	{"version", "", "print the schema version"},
+}
+
+// printMigrateUsage lists the migrate subcommands, or describes one.
+func printMigrateUsage(w io.Writer, command string) {
+	fmt.Fprintln(w, "Usage:")
+	for _, c := range migrateCommands {
+		if command == "" || command == c.name {
+			fmt.Fprintf(w, "  techblogs-api migrate %s\n\t%s\n", strings.TrimSpace(c.name+" "+c.args), c.usage)
+		}
+	}
+}
+
+// runMigrate applies, reverts or reports the embedded migrations:
+//
+//	techblogs-api migrate up|down [N]|status|version
+//
+// down reverts the latest migration, or the latest N. Arguments are checked
+// before the database is opened.
+func runMigrate(sugar *zap.SugaredLogger, args []string) {
+	if len(args) == 0 || !slices.ContainsFunc(migrateCommands, func(c migrateCommand) bool { return c.name == args[0] }) {
+		if len(args) > 0 && args[0] != "help" && args[0] != "-h" && args[0] != "--help" {
+			fmt.Fprintf(os.Stderr, "Unknown migrate command %q\n", args[0])
+		}
+		printMigrateUsage(os.Stderr, "")
+		os.Exit(2)
+	}
+
+	command := args[0]
+	fs := flag.NewFlagSet("migrate "+command, flag.ExitOnError)
+	fs.Usage = func() { printMigrateUsage(fs.Output(), command) }
+	fs.Parse(args[1:])
+
+	steps := 1
+	maxArgs := 0
+	if command == "down" {
+		maxArgs = 1
+	}
+	if fs.NArg() > maxArgs {
+		fmt.Fprintf(os.Stderr, "Unexpected arguments %q\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		os.Exit(2)
+	}
+	if command == "down" && fs.NArg() == 1 {
+		var err error
+		steps, err = strconv.Atoi(fs.Arg(0))
+		if err != nil || steps < 1 {
+			fmt.Fprintf(os.Stderr, "Invalid number of migrations to revert %q\n", fs.Arg(0))
+			fs.Usage()
+			os.Exit(2)
+		}
+	}
+
+	opened := openDatabase(sugar, false)
+	defer opened.Close()
+	migrator := opened.Migrator
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("Applied %03d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			sugar.Fatalw("Failed to migrate database", "error", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %03d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			sugar.Fatalw("Failed to revert migrations", "error", err)
+		}
+
+	case "status":
+		version, dirty, err := migrator.Version(ctx)
+		if err != nil {
+			sugar.Fatalw("Failed to read schema version", "error", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE")
+		for _, migration := range migrator.Migrations() {
+			state := "pending"
+			if migration.Version < version || (migration.Version == version && !dirty) {
+				state = "applied"
+			} else if migration.Version == version {
+				state = "dirty"
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\n", migration.Version, migration.Name, state)
+		}
+		w.Flush()
+
+	case "version":
+		version, dirty, err := migrator.Version(ctx)
+		if err != nil {
+			sugar.Fatalw("Failed to read schema version", "error", err)
+		}
+		switch {
+		case version == 0:
+			fmt.Println("none")
+		case dirty:
+			fmt.Printf("%d (dirty)\n", version)
+		default:
+			fmt.Println(version)
+		}
+	}
+}
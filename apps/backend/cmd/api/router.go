@@ -1,7 +1,6 @@
 package main
 
 import (
-	"database/sql"
 	"net/http"
 	"time"
 
@@ -10,10 +9,14 @@ import (
 	"go.uber.org/zap"
 )
 
-func registerRoutes(mux *http.ServeMux, startTime time.Time, db *sql.DB, logger *zap.SugaredLogger) {
+func registerRoutes(mux *http.ServeMux, startTime time.Time, blogsRepo blogs.Store, logger *zap.SugaredLogger) {
 	healthHandler := NewHealthHandler(startTime)
-	blogsRepo := blogs.NewRepository(db)
 	blogsHandler := NewBlogsHandler(blogsRepo)
+	shadowHandler := NewShadowHandler(blogsRepo)
+	linksHandler := NewLinksHandler(blogsRepo)
+	iconsHandler := NewIconsHandler(blogsRepo)
+	tagsHandler := NewTagsHandler(blogsRepo)
+	searchHandler := NewSearchHandler(blogsRepo)
 	homeHandler := &home.HomeHandler{Logger: *logger, Repo: blogsRepo}
 
 	// Home page
@@ -24,4 +27,11 @@ func registerRoutes(mux *http.ServeMux, startTime time.Time, db *sql.DB, logger
 	mux.HandleFunc("GET /api/blogs", blogsHandler.Read)
 	mux.HandleFunc("GET /api/blogs/rss.xml", blogsHandler.RSS)
 	mux.HandleFunc("GET /api/blogs/{collection}", blogsHandler.Read)
+	mux.HandleFunc("GET /api/blogs/{blog}/icon", iconsHandler.Read)
+	mux.HandleFunc("GET /api/tags", tagsHandler.Read)
+	mux.HandleFunc("GET /api/tags/{tag}/rss.xml", tagsHandler.RSS)
+	mux.HandleFunc("GET /api/search", searchHandler.Read)
+	mux.HandleFunc("GET /api/search/blogs", searchHandler.Blogs)
+	mux.HandleFunc("GET /api/shadow", shadowHandler.Read)
+	mux.HandleFunc("GET /api/links", linksHandler.Read)
 }
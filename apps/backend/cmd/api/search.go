@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+const (
+	defaultSearchLimit  = 20
+	defaultSuggestLimit = 8
+	maxSearchLimit      = 50
+)
+
+type SearchHandler struct {
+	repo blogs.Store
+}
+
+func NewSearchHandler(repo blogs.Store) *SearchHandler {
+	return &SearchHandler{repo: repo}
+}
+
+// Read searches the blogs and articles for ?q=, the best matches first, and
+// answers in JSON or as an HTML fragment for htmx.
+func (h *SearchHandler) Read(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Vary", "Accept")
+
+	limit, ok := searchLimit(w, r, defaultSearchLimit)
+	if !ok {
+		return
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	results, err := h.repo.Search(r.Context(), query, limit)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		results = []blogs.SearchResult{}
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "application/json") {
+		encodeSearchJSON(w, results)
+		return
+	}
+	if accept == "" || accept == "*/*" || strings.Contains(accept, "application/fragment+xml") {
+		encodeSearchHTML(w, blogs.SearchResultsTemplate, blogs.SearchResults{Query: query, Results: results})
+		return
+	}
+	http.Error(w, "Not Acceptable", http.StatusNotAcceptable)
+}
+
+// Blogs suggests the names of the blogs containing ?q=, for typeahead: in
+// JSON, or as the options of a datalist for htmx.
+func (h *SearchHandler) Blogs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Vary", "Accept")
+
+	limit, ok := searchLimit(w, r, defaultSuggestLimit)
+	if !ok {
+		return
+	}
+	names, err := h.repo.SuggestBlogNames(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if names == nil {
+		names = []string{}
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "application/json") {
+		encodeSearchJSON(w, names)
+		return
+	}
+	if accept == "" || accept == "*/*" || strings.Contains(accept, "application/fragment+xml") {
+		encodeSearchHTML(w, blogs.BlogNameOptionsTemplate, names)
+		return
+	}
+	http.Error(w, "Not Acceptable", http.StatusNotAcceptable)
+}
+
+// searchLimit reads ?limit=, capped to maxSearchLimit. It answers with an
+// error and returns false when the parameter is invalid.
+func searchLimit(w http.ResponseWriter, r *http.Request, fallback int) (int, bool) {
+	param := r.URL.Query().Get("limit")
+	if param == "" {
+		return fallback, true
+	}
+	limit, err := strconv.Atoi(param)
+	if err != nil || limit <= 0 {
+		http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+		return 0, false
+	}
+	return min(limit, maxSearchLimit), true
+}
+
+func encodeSearchJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+}
+
+func encodeSearchHTML(w http.ResponseWriter, tmpl *template.Template, data any) {
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, buffer.String())
+}
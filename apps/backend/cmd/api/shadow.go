@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// defaultShadowDays is the period shadow stats cover unless ?days= is given;
+// a blog is usually switched over after a week of agreement.
+const defaultShadowDays = 7
+
+type ShadowHandler struct {
+	repo blogs.Store
+}
+
+func NewShadowHandler(repo blogs.Store) *ShadowHandler {
+	return &ShadowHandler{repo: repo}
+}
+
+// Read reports, for each blog with a shadow strategy, how often it agreed
+// with the live extraction over the last ?days= days.
+func (h *ShadowHandler) Read(w http.ResponseWriter, r *http.Request) {
+	days := defaultShadowDays
+	if param := r.URL.Query().Get("days"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	stats, err := h.repo.GetShadowStats(r.Context(), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if stats == nil {
+		stats = []blogs.ShadowStats{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+type TagsHandler struct {
+	repo blogs.Store
+}
+
+func NewTagsHandler(repo blogs.Store) *TagsHandler {
+	return &TagsHandler{repo: repo}
+}
+
+// Read lists the tags with the number of blogs tagged with each.
+func (h *TagsHandler) Read(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.repo.GetTags(r.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []blogs.Tag{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// RSS serves the feed of the blogs tagged with {tag}, taking the same query
+// filters as /api/blogs/rss.xml.
+func (h *TagsHandler) RSS(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(r.PathValue("tag"))
+	tags, err := h.repo.GetTags(r.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var tag *blogs.Tag
+	for i := range tags {
+		if tags[i].Name == name {
+			tag = &tags[i]
+		}
+	}
+	if tag == nil {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+
+	items, err := h.repo.GetAllBlogs(r.Context())
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	items, ok := filterBlogs(w, r, blogs.FilterByTag(items, tag.Name))
+	if !ok {
+		return
+	}
+
+	encodeBlogsRSS(w, blogs.BlogFeed{Tag: tag, Blogs: items})
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+const defaultBackfillPages = 20
+
+func runBackfill(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	blogName := fs.String("blog", "", "name of the blog to backfill")
+	maxPages := fs.Int("max-pages", defaultBackfillPages, "maximum number of listing pages to follow")
+	fs.Parse(args)
+
+	if *blogName == "" {
+		log.Fatal("Missing required --blog flag")
+	}
+	if *maxPages < 1 {
+		log.Fatal("--max-pages must be at least 1")
+	}
+
+	db, repo := openRepository()
+	defer db.Close()
+	ctx := context.Background()
+
+	config, err := repo.GetBlogConfig(ctx, *blogName)
+	if err != nil {
+		log.Fatalf("Failed to get blog config: %v", err)
+	}
+	if config == nil {
+		log.Fatalf("Unknown blog: %s", *blogName)
+	}
+
+	log.Printf("Backfilling %s (%s), up to %d pages...\n", config.BlogName, config.BlogHref, *maxPages)
+
+	articles, err := backfillBlog(newHTTPClient(), *config, *maxPages)
+	if err != nil {
+		// Keep whatever was collected before the failure
+		log.Printf("Backfill of %s stopped early: %v\n", config.BlogName, err)
+	}
+
+	inserted, err := repo.InsertArticles(ctx, config.BlogName, articles)
+	if err != nil {
+		log.Fatalf("Failed to store articles: %v", err)
+	}
+
+	log.Printf("Backfill complete: %d articles found, %d new\n", len(articles), inserted)
+}
+
+// backfillBlog walks the listing pages of a blog, starting at its BlogHref,
+// and returns every article listed on them, de-duplicated by href. It stops
+// after maxPages pages, when there is no next page, or when a page brings no
+// new article. Articles collected before an error are returned with it.
+func backfillBlog(client *http.Client, config blogs.BlogConfig, maxPages int) ([]blogs.Article, error) {
+	if config.ArticleHrefSelector == "" && config.HrefRule == nil {
+		return nil, fmt.Errorf("no href selector or rule configured for %s", config.BlogName)
+	}
+
+	var articles []blogs.Article
+	seenArticles := make(map[string]bool)
+	seenPages := make(map[string]bool)
+
+	pageURL := config.BlogHref
+	for page := 1; page <= maxPages && pageURL != ""; page++ {
+		if seenPages[pageURL] {
+			break
+		}
+		seenPages[pageURL] = true
+
+		fetched, err := fetchPage(client, pageURL)
+		if err != nil {
+			return articles, fmt.Errorf("page %d (%s): %w", page, pageURL, err)
+		}
+		listed, err := listArticles(fetched, config)
+		if err != nil {
+			return articles, fmt.Errorf("page %d (%s): %w", page, pageURL, err)
+		}
+
+		found := 0
+		for _, article := range listed {
+			if seenArticles[article.Href] {
+				continue
+			}
+			seenArticles[article.Href] = true
+			articles = append(articles, article)
+			found++
+		}
+
+		log.Printf("Page %d (%s): %d new articles\n", page, pageURL, found)
+		if found == 0 {
+			break
+		}
+
+		pageURL = nextPageURL(fetched.Doc, config, fetched.URL, page+1)
+	}
+
+	return articles, nil
+}
+
+// listArticles returns every article of a listing page. Names are paired with
+// hrefs by position; when the name selector or rule matches fewer elements,
+// the text of the link itself is used. Extraction rules read every match from
+// their index on. Articles breaking the blog's validation rules are left out,
+// but invalid validation rules are an error.
+func listArticles(page *fetchedPage, config blogs.BlogConfig) ([]blogs.Article, error) {
+	links, err := listingMatches(page.Doc, config.HrefRule, config.ArticleHrefSelector)
+	if err != nil {
+		return nil, fmt.Errorf("href rule: %w", err)
+	}
+	names, err := listingMatches(page.Doc, config.NameRule, config.ArticleNameSelector)
+	if err != nil {
+		return nil, fmt.Errorf("name rule: %w", err)
+	}
+
+	var articles []blogs.Article
+	for i, link := range links {
+		var href string
+		if config.HrefRule != nil {
+			href, _ = ruleValue(link, *config.HrefRule, "href")
+		} else {
+			href, _ = link.Attr("href")
+		}
+		if strings.TrimSpace(href) == "" {
+			continue
+		}
+
+		var name string
+		switch {
+		case i >= len(names):
+		case config.NameRule != nil:
+			name, _ = ruleValue(names[i], *config.NameRule, "")
+		default:
+			name = articleText(names[i])
+		}
+		if name == "" {
+			name = articleText(link)
+		}
+		if name == "" {
+			continue
+		}
+
+		// Relative links are resolved against where the page was found
+		href = resolveAgainst(page.URL, href)
+		name, err := applyRules(config.Rules, name, href)
+		if errors.Is(err, errRuleViolation) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		articles = append(articles, blogs.Article{Name: name, Href: href})
+	}
+
+	return articles, nil
+}
+
+// listingMatches returns every element an extraction rule matches from its
+// index on, or else every element the selector matches. Values a rule fails
+// to read on some of them are left to the caller, but a rule that does not
+// compile or matches nothing is an error.
+func listingMatches(doc *goquery.Document, rule *blogs.ExtractionRule, selector string) ([]*goquery.Selection, error) {
+	var matches []*goquery.Selection
+	if rule == nil {
+		if selector != "" {
+			doc.Find(selector).Each(func(_ int, match *goquery.Selection) {
+				matches = append(matches, match)
+			})
+		}
+		return matches, nil
+	}
+
+	if rule.Regex != "" {
+		if _, err := regexp.Compile(rule.Regex); err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", rule.Regex, err)
+		}
+	}
+	return selectRuleMatches(doc, *rule)
+}
+
+// nextPageURL returns the URL of the listing page following currentURL, or
+// an empty string when the blog has no more pages.
+func nextPageURL(doc *goquery.Document, config blogs.BlogConfig, currentURL string, nextPage int) string {
+	if config.PageURLTemplate != "" {
+		return strings.ReplaceAll(config.PageURLTemplate, "{page}", strconv.Itoa(nextPage))
+	}
+
+	if config.NextPageSelector == "" {
+		return ""
+	}
+
+	href, exists := doc.Find(config.NextPageSelector).First().Attr("href")
+	if !exists || strings.TrimSpace(href) == "" {
+		return ""
+	}
+
+	// Next links are often relative to the current page (e.g. "?page=2")
+	return resolveAgainst(currentURL, href)
+}
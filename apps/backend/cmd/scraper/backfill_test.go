@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+func TestBackfillBlog(t *testing.T) {
+	pages := map[string]string{
+		"/blog": `<html><body>
+			<a href="/post-3" class="post">Third Post</a>
+			<a href="/post-2" class="post">Second Post</a>
+			<a href="?page=2" class="next">Older</a>
+		</body></html>`,
+		"/blog?page=2": `<html><body>
+			<a href="/post-2" class="post">Second Post</a>
+			<a href="/post-1" class="post">First Post</a>
+			<a href="?page=3" class="next">Older</a>
+		</body></html>`,
+		"/blog?page=3": `<html><body>
+			<a href="/post-1" class="post">First Post</a>
+		</body></html>`,
+	}
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	config := blogs.BlogConfig{
+		BlogName:            "Test Blog",
+		BlogHref:            server.URL + "/blog",
+		ArticleHrefSelector: "a.post",
+		ArticleNameSelector: "a.post",
+		NextPageSelector:    "a.next",
+	}
+
+	articles, err := backfillBlog(server.Client(), config, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []blogs.Article{
+		{Name: "Third Post", Href: server.URL + "/post-3"},
+		{Name: "Second Post", Href: server.URL + "/post-2"},
+		{Name: "First Post", Href: server.URL + "/post-1"},
+	}
+	if len(articles) != len(expected) {
+		t.Fatalf("got %d articles, want %d: %+v", len(articles), len(expected), articles)
+	}
+	for i := range expected {
+		if articles[i] != expected[i] {
+			t.Errorf("article %d = %+v, want %+v", i, articles[i], expected[i])
+		}
+	}
+
+	// The third page only repeats a known article, so the walk stops there
+	if requests != 3 {
+		t.Errorf("made %d requests, want 3", requests)
+	}
+}
+
+func TestBackfillBlog_PageURLTemplate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := strings.TrimPrefix(r.URL.Path, "/page/")
+		if r.URL.Path == "/" {
+			page = "1"
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><a href="/post-%s" class="post">Post %s</a></body></html>`, page, page)
+	}))
+	defer server.Close()
+
+	config := blogs.BlogConfig{
+		BlogName:            "Test Blog",
+		BlogHref:            server.URL + "/",
+		ArticleHrefSelector: "a.post",
+		ArticleNameSelector: "a.post",
+		PageURLTemplate:     server.URL + "/page/{page}",
+	}
+
+	articles, err := backfillBlog(server.Client(), config, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(articles) != 4 {
+		t.Fatalf("got %d articles, want 4 (page limit)", len(articles))
+	}
+	if articles[3].Href != server.URL+"/post-4" {
+		t.Errorf("last href = %q, want %q", articles[3].Href, server.URL+"/post-4")
+	}
+}
+
+func TestBackfillBlog_KeepsArticlesOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>
+			<a href="/post-1" class="post">First Post</a>
+			<a href="/page/2" class="next">Older</a>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	config := blogs.BlogConfig{
+		BlogName:            "Test Blog",
+		BlogHref:            server.URL + "/",
+		ArticleHrefSelector: "a.post",
+		ArticleNameSelector: "a.post",
+		NextPageSelector:    "a.next",
+	}
+
+	articles, err := backfillBlog(server.Client(), config, 10)
+	if err == nil || !strings.Contains(err.Error(), "bad status code: 500") {
+		t.Fatalf("expected bad status error, got %v", err)
+	}
+	if len(articles) != 1 {
+		t.Errorf("got %d articles, want 1", len(articles))
+	}
+}
+
+func TestListArticles_NameFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>
+			<a href="/post-1" class="card"><h2>First</h2></a>
+			<a href="/post-2" class="card"><span>Second</span></a>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	page, err := fetchPage(server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config := blogs.BlogConfig{
+		BlogHref:            server.URL,
+		ArticleHrefSelector: "a.card",
+		ArticleNameSelector: "a.card h2",
+	}
+
+	articles, err := listArticles(page, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(articles) != 2 {
+		t.Fatalf("got %d articles, want 2", len(articles))
+	}
+	if articles[0].Name != "First" || articles[1].Name != "Second" {
+		t.Errorf("names = %q, %q; want %q, %q", articles[0].Name, articles[1].Name, "First", "Second")
+	}
+}
+
+func TestListArticles_NestedListing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>
+			<a href="post-2" class="post">Second</a>
+			<a href="/blog/post-1" class="post">First</a>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	page, err := fetchPage(server.Client(), server.URL+"/blog/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config := blogs.BlogConfig{
+		BlogHref:            server.URL + "/blog/",
+		ArticleHrefSelector: "a.post",
+	}
+
+	articles, err := listArticles(page, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []blogs.Article{
+		{Name: "Second", Href: server.URL + "/blog/post-2"},
+		{Name: "First", Href: server.URL + "/blog/post-1"},
+	}
+	if len(articles) != len(expected) {
+		t.Fatalf("got %d articles, want %d: %+v", len(articles), len(expected), articles)
+	}
+	for i := range expected {
+		if articles[i] != expected[i] {
+			t.Errorf("article %d = %+v, want %+v", i, articles[i], expected[i])
+		}
+	}
+}
+
+func TestListArticles_Rules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>
+			<div class="featured" data-href="/featured"><h2>Featured: Pinned</h2></div>
+			<div class="entry" data-href="/post-2"><h2>Post: Second</h2></div>
+			<div class="entry" data-href="/post-1"><h2>Post: First</h2></div>
+			<div class="entry"><h2>Post: No link</h2></div>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	page, err := fetchPage(server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config := blogs.BlogConfig{
+		BlogHref: server.URL,
+		HrefRule: &blogs.ExtractionRule{Engine: blogs.EngineXPath, Selector: "//div[@class]", Index: 1, Attribute: "data-href"},
+		NameRule: &blogs.ExtractionRule{Selector: "div h2", Index: 1, Regex: `^Post: (.+)$`},
+	}
+
+	articles, err := listArticles(page, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []blogs.Article{
+		{Name: "Second", Href: server.URL + "/post-2"},
+		{Name: "First", Href: server.URL + "/post-1"},
+	}
+	if len(articles) != len(expected) {
+		t.Fatalf("got %d articles, want %d: %+v", len(articles), len(expected), articles)
+	}
+	for i := range expected {
+		if articles[i] != expected[i] {
+			t.Errorf("article %d = %+v, want %+v", i, articles[i], expected[i])
+		}
+	}
+
+	for _, rule := range []blogs.ExtractionRule{
+		{Selector: "div h2", Regex: "(unclosed"},
+		{Engine: blogs.EngineXPath, Selector: "//div["},
+		{Selector: ".missing"},
+	} {
+		config.NameRule = &rule
+		if _, err := listArticles(page, config); err == nil {
+			t.Errorf("expected an error for name rule %+v", rule)
+		}
+	}
+}
+
+func TestListArticles_ValidationRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>
+			<a href="/posts/kept" class="post">Kept</a>
+			<a href="/about" class="post">About</a>
+			<a href="/posts/more" class="post">Read more</a>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	page, err := fetchPage(server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config := blogs.BlogConfig{
+		BlogHref:            server.URL,
+		ArticleHrefSelector: "a.post",
+		Rules:               blogs.ValidationRules{HrefPattern: "/posts/", TitleBlocklist: []string{"read more"}},
+	}
+	articles, err := listArticles(page, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(articles) != 1 || articles[0].Name != "Kept" {
+		t.Errorf("articles = %+v, want only Kept", articles)
+	}
+
+	// A broken rule fails the listing instead of dropping every article
+	config.Rules.HrefPattern = "(unclosed"
+	if _, err := listArticles(page, config); err == nil || !strings.Contains(err.Error(), "invalid href pattern") {
+		t.Errorf("error = %v, want an invalid href pattern", err)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/htmlquery"
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// extractLatest reads the latest article of a fetched listing page, using the
+// blog's extraction rules when it has some and its plain selectors otherwise.
+func extractLatest(page *fetchedPage, config blogs.BlogConfig) (articleName string, articleHref string, err error) {
+	doc := page.Doc
+	if config.HrefRule != nil {
+		articleHref, err = applyExtractionRule(doc, *config.HrefRule, "href")
+		if err != nil {
+			return "", "", fmt.Errorf("href rule: %w", err)
+		}
+	} else {
+		// Find the article href using CSS selector
+		hrefSelection := doc.Find(config.ArticleHrefSelector).First()
+		if hrefSelection.Length() == 0 {
+			return "", "", fmt.Errorf("no articles found with href selector: %s", config.ArticleHrefSelector)
+		}
+
+		// Extract article href from the link
+		var exists bool
+		articleHref, exists = hrefSelection.Attr("href")
+		if !exists {
+			return "", "", fmt.Errorf("article href not found")
+		}
+	}
+
+	// Relative links are resolved against where the page was found
+	articleHref = resolveAgainst(page.URL, articleHref)
+
+	if config.NameRule != nil {
+		articleName, err = applyExtractionRule(doc, *config.NameRule, "")
+		if err != nil {
+			return "", "", fmt.Errorf("name rule: %w", err)
+		}
+	} else {
+		// Find the article name using CSS selector
+		nameSelection := doc.Find(config.ArticleNameSelector).First()
+		if nameSelection.Length() == 0 {
+			return "", "", fmt.Errorf("no articles found with name selector: %s", config.ArticleNameSelector)
+		}
+
+		articleName = articleText(nameSelection)
+	}
+
+	if articleName == "" {
+		return "", "", fmt.Errorf("article name is empty")
+	}
+
+	articleName, err = applyRules(config.Rules, articleName, articleHref)
+	if err != nil {
+		return "", "", err
+	}
+
+	return articleName, articleHref, nil
+}
+
+// hrefMatch returns the element the blog's href rule or selector matches,
+// or nil when nothing matches.
+func hrefMatch(doc *goquery.Document, config blogs.BlogConfig) *goquery.Selection {
+	if config.HrefRule != nil {
+		match, err := selectRuleMatch(doc, *config.HrefRule)
+		if err != nil {
+			return nil
+		}
+		return match
+	}
+
+	if config.ArticleHrefSelector == "" {
+		return nil
+	}
+	match := doc.Find(config.ArticleHrefSelector).First()
+	if match.Length() == 0 {
+		return nil
+	}
+	return match
+}
+
+// maxMatchedElementSize bounds the HTML kept for a matched element.
+const maxMatchedElementSize = 4 << 10
+
+// matchedElementHTML returns the HTML of the element the href rule or
+// selector matches, truncated to maxMatchedElementSize.
+func matchedElementHTML(doc *goquery.Document, config blogs.BlogConfig) string {
+	match := hrefMatch(doc, config)
+	if match == nil {
+		return ""
+	}
+	html, err := goquery.OuterHtml(match)
+	if err != nil {
+		return ""
+	}
+	if len(html) > maxMatchedElementSize {
+		html = html[:maxMatchedElementSize]
+	}
+	return html
+}
+
+// applyExtractionRule evaluates one extraction rule against a document.
+// defaultAttribute is read when the rule names no attribute; an empty
+// attribute (or "text") reads the element text, with the usual aria-label
+// fallback.
+func applyExtractionRule(doc *goquery.Document, rule blogs.ExtractionRule, defaultAttribute string) (string, error) {
+	selection, err := selectRuleMatch(doc, rule)
+	if err != nil {
+		return "", err
+	}
+	return ruleValue(selection, rule, defaultAttribute)
+}
+
+// ruleValue reads the value of a rule's match, as applyExtractionRule does.
+func ruleValue(selection *goquery.Selection, rule blogs.ExtractionRule, defaultAttribute string) (string, error) {
+	attribute := rule.Attribute
+	if attribute == "" {
+		attribute = defaultAttribute
+	}
+
+	var value string
+	if attribute == "" || attribute == "text" {
+		value = articleText(selection)
+	} else {
+		var exists bool
+		value, exists = selection.Attr(attribute)
+		if !exists {
+			return "", fmt.Errorf("attribute %q not found on match %d of %q", attribute, rule.Index, rule.Selector)
+		}
+	}
+
+	if rule.Regex != "" {
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex %q: %w", rule.Regex, err)
+		}
+		groups := re.FindStringSubmatch(value)
+		if groups == nil {
+			return "", fmt.Errorf("regex %q does not match %q", rule.Regex, value)
+		}
+		// Keep the first capture group, or the whole match without groups
+		value = groups[0]
+		if len(groups) > 1 {
+			value = groups[1]
+		}
+	}
+
+	return strings.TrimSpace(value), nil
+}
+
+// selectRuleMatch returns the element picked by a rule's selector and index.
+func selectRuleMatch(doc *goquery.Document, rule blogs.ExtractionRule) (*goquery.Selection, error) {
+	matches, err := selectRuleMatches(doc, rule)
+	if err != nil {
+		return nil, err
+	}
+	return matches[0], nil
+}
+
+// selectRuleMatches returns the elements a rule's selector matches from its
+// index on, failing when there is none at the index.
+func selectRuleMatches(doc *goquery.Document, rule blogs.ExtractionRule) ([]*goquery.Selection, error) {
+	if rule.Selector == "" {
+		return nil, fmt.Errorf("rule has no selector")
+	}
+	if rule.Index < 0 {
+		return nil, fmt.Errorf("invalid rule index %d", rule.Index)
+	}
+
+	switch rule.Engine {
+	case "", blogs.EngineCSS:
+		matches := doc.Find(rule.Selector)
+		if rule.Index >= matches.Length() {
+			return nil, fmt.Errorf("no match %d for css selector %q (%d found)", rule.Index, rule.Selector, matches.Length())
+		}
+		var selections []*goquery.Selection
+		for i := rule.Index; i < matches.Length(); i++ {
+			selections = append(selections, matches.Eq(i))
+		}
+		return selections, nil
+
+	case blogs.EngineXPath:
+		if len(doc.Nodes) == 0 {
+			return nil, fmt.Errorf("empty document")
+		}
+		nodes, err := htmlquery.QueryAll(doc.Nodes[0], rule.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xpath %q: %w", rule.Selector, err)
+		}
+		if rule.Index >= len(nodes) {
+			return nil, fmt.Errorf("no match %d for xpath %q (%d found)", rule.Index, rule.Selector, len(nodes))
+		}
+		// XPath may select attribute nodes, which live outside the document
+		// tree, so each match is wrapped on its own
+		var selections []*goquery.Selection
+		for _, node := range nodes[rule.Index:] {
+			selections = append(selections, goquery.NewDocumentFromNode(node).Selection)
+		}
+		return selections, nil
+
+	default:
+		return nil, fmt.Errorf("unknown extraction engine %q", rule.Engine)
+	}
+}
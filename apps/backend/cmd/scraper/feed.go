@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// feedDocument decodes RSS 2.0, RSS 1.0 (RDF) and Atom feeds alike.
+type feedDocument struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssItem struct {
+	Title string `xml:"title"`
+	Link  string `xml:"link"`
+	GUID  string `xml:"guid"`
+}
+
+type atomEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+}
+
+// parseFeed returns the items of an RSS or Atom feed, in feed order.
+func parseFeed(body []byte) ([]blogs.Article, error) {
+	var feed feedDocument
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	// Feeds are not always UTF-8; the titles used here survive a lenient read
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := decoder.Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+
+	var articles []blogs.Article
+
+	items := append(feed.Channel.Items, feed.Items...)
+	for _, item := range items {
+		href := strings.TrimSpace(item.Link)
+		if href == "" && strings.HasPrefix(item.GUID, "http") {
+			href = strings.TrimSpace(item.GUID)
+		}
+		if href == "" {
+			continue
+		}
+		articles = append(articles, blogs.Article{Name: cleanTitle(item.Title), Href: href})
+	}
+
+	for _, entry := range feed.Entries {
+		var href string
+		for _, link := range entry.Links {
+			if link.Rel == "" || link.Rel == "alternate" {
+				href = strings.TrimSpace(link.Href)
+				break
+			}
+		}
+		if href == "" {
+			continue
+		}
+		articles = append(articles, blogs.Article{Name: cleanTitle(entry.Title), Href: href})
+	}
+
+	return articles, nil
+}
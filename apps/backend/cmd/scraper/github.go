@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+const (
+	defaultGitHubAPIURL = "https://api.github.com"
+	// maxGitHubResponseSize bounds the size of GitHub API responses; a page
+	// of 100 repositories stays well under it.
+	maxGitHubResponseSize = 4 << 20
+)
+
+// gitHubClient reads profiles from a GitHub-compatible REST API.
+type gitHubClient struct {
+	client  *http.Client
+	baseURL string
+	// token authenticates requests when set, for a higher rate limit.
+	token string
+}
+
+// gitHubRateLimitError reports an exhausted API rate limit, which ends the
+// enrichment run.
+type gitHubRateLimitError struct {
+	Reset time.Time
+}
+
+func (e *gitHubRateLimitError) Error() string {
+	if e.Reset.IsZero() {
+		return "GitHub API rate limit exceeded"
+	}
+	return fmt.Sprintf("GitHub API rate limit exceeded until %s", e.Reset.Format(time.RFC3339))
+}
+
+// runGitHub fetches the GitHub profile of individual bloggers and stores it.
+// The API is read from GITHUB_API_URL, authenticated with GITHUB_TOKEN when
+// set.
+func runGitHub(args []string) {
+	fs := flag.NewFlagSet("github", flag.ExitOnError)
+	blogName := fs.String("blog", "", "only enrich this blog (default: all individuals)")
+	maxAge := fs.Duration("max-age", 7*24*time.Hour, "refresh profiles fetched longer ago than this")
+	topRepos := fs.Int("repos", 3, "number of most starred repositories kept per profile")
+	fs.Parse(args)
+
+	baseURL := os.Getenv("GITHUB_API_URL")
+	if baseURL == "" {
+		baseURL = defaultGitHubAPIURL
+	}
+	gh := gitHubClient{client: newHTTPClient(), baseURL: strings.TrimSuffix(baseURL, "/"), token: os.Getenv("GITHUB_TOKEN")}
+
+	db, repo := openRepository()
+	defer db.Close()
+	ctx := context.Background()
+
+	configs, err := repo.GetAllBlogConfigs(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get blog configs: %v", err)
+	}
+	profiles, err := repo.GetGitHubProfiles(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get GitHub profiles: %v", err)
+	}
+
+	enriched, failed := 0, 0
+	for _, config := range configs {
+		if *blogName != "" && config.BlogName != *blogName {
+			continue
+		}
+		if config.Kind != blogs.Individual || config.Archived || config.Disabled {
+			continue
+		}
+		login := gitHubLogin(config.GitHubHref)
+		if login == "" {
+			continue
+		}
+		if profile, ok := profiles[config.BlogName]; ok && *blogName == "" &&
+			strings.EqualFold(profile.Login, login) && time.Since(profile.FetchedAt) < *maxAge {
+			continue
+		}
+
+		profile, err := gh.profile(login, *topRepos)
+		if err != nil {
+			var rateLimited *gitHubRateLimitError
+			if errors.As(err, &rateLimited) {
+				log.Printf("Stopping: %v\n", err)
+				break
+			}
+			failed++
+			log.Printf("Error fetching GitHub profile of %s (%s): %v\n", config.BlogName, login, err)
+			continue
+		}
+
+		if err := repo.SaveGitHubProfile(ctx, config.BlogName, profile); err != nil {
+			failed++
+			log.Printf("Error saving GitHub profile of %s: %v\n", config.BlogName, err)
+			continue
+		}
+		enriched++
+	}
+
+	log.Printf("Enriched %d GitHub profiles, %d failed\n", enriched, failed)
+}
+
+// gitHubLogin returns the user a github.com profile link points to, or an
+// empty string for other links.
+func gitHubLogin(gitHubHref string) string {
+	u, err := url.Parse(gitHubHref)
+	if err != nil || !strings.EqualFold(u.Hostname(), "github.com") {
+		return ""
+	}
+	login, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/")
+	return login
+}
+
+// profile reads a user and their most starred repositories, forks excluded.
+// The REST API does not expose pinned repositories.
+func (gh gitHubClient) profile(login string, topRepos int) (blogs.GitHubProfile, error) {
+	var user struct {
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		Bio       string `json:"bio"`
+		Followers int    `json:"followers"`
+	}
+	if err := gh.get("/users/"+url.PathEscape(login), &user); err != nil {
+		return blogs.GitHubProfile{}, err
+	}
+
+	var repos []struct {
+		Name        string `json:"name"`
+		HTMLURL     string `json:"html_url"`
+		Description string `json:"description"`
+		Stars       int    `json:"stargazers_count"`
+		Fork        bool   `json:"fork"`
+		Archived    bool   `json:"archived"`
+	}
+	if err := gh.get("/users/"+url.PathEscape(login)+"/repos?type=owner&per_page=100", &repos); err != nil {
+		return blogs.GitHubProfile{}, err
+	}
+
+	profile := blogs.GitHubProfile{
+		Login:     user.Login,
+		Name:      strings.TrimSpace(user.Name),
+		Bio:       strings.Join(strings.Fields(user.Bio), " "),
+		Followers: user.Followers,
+		TopRepos:  []blogs.GitHubRepo{},
+		FetchedAt: time.Now(),
+	}
+	for _, repo := range repos {
+		if repo.Fork || repo.Archived || repo.Stars == 0 {
+			continue
+		}
+		profile.TopRepos = append(profile.TopRepos, blogs.GitHubRepo{
+			Name:        repo.Name,
+			Href:        repo.HTMLURL,
+			Description: strings.TrimSpace(repo.Description),
+			Stars:       repo.Stars,
+		})
+	}
+	slices.SortStableFunc(profile.TopRepos, func(a, b blogs.GitHubRepo) int {
+		return b.Stars - a.Stars
+	})
+	if len(profile.TopRepos) > topRepos {
+		profile.TopRepos = profile.TopRepos[:topRepos]
+	}
+
+	return profile, nil
+}
+
+// get decodes the JSON response of an API path into v.
+func (gh gitHubClient) get(path string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, gh.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := checkScheme(req); err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if gh.token != "" {
+		req.Header.Set("Authorization", "Bearer "+gh.token)
+	}
+
+	resp, err := gh.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call GitHub API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) &&
+		resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		rateLimited := &gitHubRateLimitError{}
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			rateLimited.Reset = time.Unix(reset, 0)
+		}
+		return rateLimited
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GitHub API %s: bad status code: %d", path, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxGitHubResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read GitHub API response: %w", err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to decode GitHub API response: %w", err)
+	}
+	return nil
+}
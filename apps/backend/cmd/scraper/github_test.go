@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGitHubLogin(t *testing.T) {
+	tests := []struct {
+		href     string
+		expected string
+	}{
+		{"https://github.com/octocat", "octocat"},
+		{"https://github.com/octocat/", "octocat"},
+		{"https://github.com/octocat/hello-world", "octocat"},
+		{"https://GitHub.com/octocat", "octocat"},
+		{"https://gitlab.com/octocat", ""},
+		{"https://github.com/", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := gitHubLogin(tt.href); got != tt.expected {
+			t.Errorf("gitHubLogin(%q) = %q, want %q", tt.href, got, tt.expected)
+		}
+	}
+}
+
+func TestGitHubProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want the token", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v3/users/octocat":
+			w.Write([]byte(`{"login": "octocat", "name": " The Octocat ", "bio": "Builds\nthings", "followers": 42}`))
+		case "/api/v3/users/octocat/repos":
+			w.Write([]byte(`[
+				{"name": "small", "html_url": "https://github.com/octocat/small", "stargazers_count": 3},
+				{"name": "fork", "html_url": "https://github.com/octocat/fork", "stargazers_count": 900, "fork": true},
+				{"name": "popular", "html_url": "https://github.com/octocat/popular", "description": "Popular", "stargazers_count": 120},
+				{"name": "unstarred", "html_url": "https://github.com/octocat/unstarred"},
+				{"name": "medium", "html_url": "https://github.com/octocat/medium", "stargazers_count": 40}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	gh := gitHubClient{client: newHTTPClient(), baseURL: server.URL + "/api/v3", token: "secret"}
+	profile, err := gh.profile("octocat", 2)
+	if err != nil {
+		t.Fatalf("profile() error = %v", err)
+	}
+
+	if profile.Login != "octocat" || profile.Name != "The Octocat" || profile.Bio != "Builds things" || profile.Followers != 42 {
+		t.Errorf("profile() = %+v", profile)
+	}
+	if len(profile.TopRepos) != 2 || profile.TopRepos[0].Name != "popular" || profile.TopRepos[1].Name != "medium" {
+		t.Fatalf("TopRepos = %+v, want popular then medium", profile.TopRepos)
+	}
+	if profile.TopRepos[0].Description != "Popular" || profile.TopRepos[0].Stars != 120 {
+		t.Errorf("TopRepos[0] = %+v", profile.TopRepos[0])
+	}
+
+	if _, err := gh.profile("ghost", 2); err == nil {
+		t.Error("profile() error = nil for an unknown user")
+	}
+}
+
+func TestGitHubProfile_RateLimited(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	gh := gitHubClient{client: newHTTPClient(), baseURL: server.URL}
+	_, err := gh.profile("octocat", 3)
+
+	var rateLimited *gitHubRateLimitError
+	if !errors.As(err, &rateLimited) {
+		t.Fatalf("profile() error = %v, want a rate limit error", err)
+	}
+	if !rateLimited.Reset.Equal(reset) {
+		t.Errorf("Reset = %v, want %v", rateLimited.Reset, reset)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// iconSize is the width and height of stored icons, twice the size they
+	// are displayed at for high-density screens.
+	iconSize = 64
+	// maxIconSize bounds the size of downloaded icon files.
+	maxIconSize = 1 << 20
+	// maxIconDimension bounds the width and height of decoded icons.
+	maxIconDimension = 2048
+	// iconMaxAge is how long an icon is kept before being downloaded again.
+	iconMaxAge = 30 * 24 * time.Hour
+)
+
+// icoMagic starts ICO files: a reserved zero word, then type 1 for icons.
+var icoMagic = []byte{0, 0, 1, 0}
+
+// pngMagic starts PNG files, which ICO files may embed.
+var pngMagic = []byte("\x89PNG\r\n\x1a\n")
+
+// iconSources returns the URLs to take a blog icon from, in order of
+// preference: the GitHub avatar of individuals, the icon declared by the
+// blog page, and the conventional /favicon.ico.
+func iconSources(config blogs.BlogConfig, pageURL string, metadata blogs.BlogMetadata) []string {
+	var sources []string
+	if config.Kind == blogs.Individual {
+		if avatar := gitHubAvatar(config.GitHubHref); avatar != "" {
+			sources = append(sources, avatar)
+		}
+	}
+	if metadata.FaviconHref != "" {
+		sources = append(sources, metadata.FaviconHref)
+	}
+	if favicon := resolveAgainst(pageURL, "/favicon.ico"); favicon != metadata.FaviconHref {
+		sources = append(sources, favicon)
+	}
+	return sources
+}
+
+// gitHubAvatar returns the avatar URL of the GitHub user or organization a
+// profile link points to, or an empty string for other links.
+func gitHubAvatar(gitHubHref string) string {
+	login := gitHubLogin(gitHubHref)
+	if login == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://github.com/%s.png?size=%d", url.PathEscape(login), iconSize)
+}
+
+// refreshIcon downloads the icon of a blog when it has none, when its
+// preferred source changed or when it is older than iconMaxAge. A failed
+// download keeps the current icon.
+func refreshIcon(ctx context.Context, client *http.Client, repo blogs.Store, config blogs.BlogConfig, sources []string) {
+	if len(sources) == 0 {
+		return
+	}
+
+	current, err := repo.GetBlogIcon(ctx, config.BlogName)
+	if err != nil {
+		log.Printf("Error getting icon of %s: %v\n", config.BlogName, err)
+		return
+	}
+	if current != nil && current.SourceURL == sources[0] && time.Since(current.FetchedAt) < iconMaxAge {
+		return
+	}
+
+	var errs []string
+	for _, source := range sources {
+		if current != nil && source == current.SourceURL && time.Since(current.FetchedAt) < iconMaxAge {
+			// Sources preferred over the current one are still unavailable
+			return
+		}
+
+		content, err := downloadIcon(client, source)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", source, err))
+			continue
+		}
+
+		hash := sha256.Sum256(content)
+		icon := blogs.BlogIcon{
+			BlogName:    config.BlogName,
+			SourceURL:   source,
+			Content:     content,
+			ContentHash: hex.EncodeToString(hash[:]),
+		}
+		if err := repo.SaveBlogIcon(ctx, icon); err != nil {
+			log.Printf("Error saving icon of %s: %v\n", config.BlogName, err)
+		}
+		return
+	}
+
+	log.Printf("No icon found for %s: %s\n", config.BlogName, strings.Join(errs, "; "))
+}
+
+// downloadIcon fetches an icon and normalizes it into a PNG.
+func downloadIcon(client *http.Client, source string) ([]byte, error) {
+	body, _, err := fetch(client, source, "image/png, image/x-icon, image/*;q=0.8")
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxIconSize {
+		return nil, fmt.Errorf("icon larger than %d bytes", maxIconSize)
+	}
+	return normalizeIcon(body)
+}
+
+// normalizeIcon decodes an icon in any of PNG, JPEG, GIF, WebP, BMP and ICO,
+// and re-encodes it as an iconSize square PNG. Non-square icons are centered
+// on a transparent background.
+func normalizeIcon(data []byte) ([]byte, error) {
+	img, err := decodeIcon(data)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, fmt.Errorf("empty icon")
+	}
+	width, height := iconSize, iconSize
+	if bounds.Dx() > bounds.Dy() {
+		height = max(1, iconSize*bounds.Dy()/bounds.Dx())
+	} else if bounds.Dy() > bounds.Dx() {
+		width = max(1, iconSize*bounds.Dx()/bounds.Dy())
+	}
+	x, y := (iconSize-width)/2, (iconSize-height)/2
+
+	icon := image.NewNRGBA(image.Rect(0, 0, iconSize, iconSize))
+	draw.CatmullRom.Scale(icon, image.Rect(x, y, x+width, y+height), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, icon); err != nil {
+		return nil, fmt.Errorf("failed to encode icon: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeIcon decodes an icon, refusing images larger than maxIconDimension
+// before decoding their pixels.
+func decodeIcon(data []byte) (image.Image, error) {
+	if bytes.HasPrefix(data, icoMagic) {
+		return decodeICO(data)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if bytes.Contains(data[:min(len(data), 512)], []byte("<svg")) {
+			return nil, fmt.Errorf("SVG icons are not supported")
+		}
+		return nil, fmt.Errorf("failed to decode icon: %w", err)
+	}
+	if config.Width > maxIconDimension || config.Height > maxIconDimension {
+		return nil, fmt.Errorf("%s icon too large: %dx%d", format, config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s icon: %w", format, err)
+	}
+	return img, nil
+}
+
+// decodeICO decodes the largest image of an ICO file. Images are stored
+// either as PNG files or as BMP pixel data without file header, twice as
+// high as the image since an AND transparency mask follows the colors.
+func decodeICO(data []byte) (image.Image, error) {
+	if len(data) < 6 {
+		return nil, fmt.Errorf("truncated ICO header")
+	}
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < 6+16*count {
+		return nil, fmt.Errorf("invalid ICO directory")
+	}
+
+	// Pick the largest entry, with the most colors among equally large ones
+	best, bestWidth, bestDepth := -1, 0, 0
+	for i := range count {
+		entry := data[6+16*i : 6+16*(i+1)]
+		width := int(entry[0])
+		if width == 0 {
+			width = 256
+		}
+		depth := int(binary.LittleEndian.Uint16(entry[6:8]))
+		if width > bestWidth || (width == bestWidth && depth > bestDepth) {
+			best, bestWidth, bestDepth = i, width, depth
+		}
+	}
+
+	entry := data[6+16*best : 6+16*(best+1)]
+	size := int64(binary.LittleEndian.Uint32(entry[8:12]))
+	offset := int64(binary.LittleEndian.Uint32(entry[12:16]))
+	if offset+size > int64(len(data)) {
+		return nil, fmt.Errorf("ICO image out of bounds")
+	}
+	entryData := data[offset : offset+size]
+
+	if bytes.HasPrefix(entryData, pngMagic) {
+		return decodeIcon(entryData)
+	}
+	return decodeDIB(entryData)
+}
+
+// decodeDIB decodes the BMP pixel data of an ICO image. 32-bit images carry
+// their own alpha channel; other depths are decoded as BMP files and made
+// transparent where their AND mask is set.
+func decodeDIB(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, fmt.Errorf("truncated ICO bitmap header")
+	}
+	headerSize := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+	depth := int(binary.LittleEndian.Uint16(data[14:16]))
+	compression := binary.LittleEndian.Uint32(data[16:20])
+	colorsUsed := int(binary.LittleEndian.Uint32(data[32:36]))
+
+	if headerSize < 40 || headerSize > len(data) || compression != 0 {
+		return nil, fmt.Errorf("unsupported ICO bitmap")
+	}
+	if width <= 0 || height <= 0 || width > maxIconDimension || height > maxIconDimension {
+		return nil, fmt.Errorf("invalid ICO bitmap size %dx%d", width, height)
+	}
+
+	if depth == 32 {
+		return decodeDIB32(data[headerSize:], width, height)
+	}
+
+	paletteSize := 0
+	if depth <= 8 {
+		paletteSize = 1 << depth
+		if colorsUsed > 0 {
+			paletteSize = colorsUsed
+		}
+	}
+	colorsEnd := headerSize + 4*paletteSize + dibStride(width, depth)*height
+
+	// Turn the bitmap into a BMP file holding the colors only
+	const fileHeaderSize = 14
+	bmp := make([]byte, fileHeaderSize, fileHeaderSize+len(data))
+	copy(bmp, "BM")
+	binary.LittleEndian.PutUint32(bmp[2:6], uint32(fileHeaderSize+len(data)))
+	binary.LittleEndian.PutUint32(bmp[10:14], uint32(fileHeaderSize+headerSize+4*paletteSize))
+	bmp = append(bmp, data...)
+	binary.LittleEndian.PutUint32(bmp[fileHeaderSize+8:fileHeaderSize+12], uint32(height))
+
+	colors, _, err := image.Decode(bytes.NewReader(bmp))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ICO bitmap: %w", err)
+	}
+
+	icon := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(icon, icon.Bounds(), colors, image.Point{}, draw.Src)
+
+	maskStride := dibStride(width, 1)
+	if len(data) < colorsEnd+maskStride*height {
+		// Icons without a mask are opaque
+		return icon, nil
+	}
+	mask := data[colorsEnd:]
+	for y := range height {
+		row := mask[(height-1-y)*maskStride:]
+		for x := range width {
+			if row[x/8]&(0x80>>(x%8)) != 0 {
+				icon.SetNRGBA(x, y, color.NRGBA{})
+			}
+		}
+	}
+	return icon, nil
+}
+
+// decodeDIB32 decodes bottom-up BGRA pixels. Old icons leave the alpha
+// channel empty and rely on their mask, so they are taken as opaque.
+func decodeDIB32(pixels []byte, width, height int) (image.Image, error) {
+	stride := dibStride(width, 32)
+	if len(pixels) < stride*height {
+		return nil, fmt.Errorf("truncated ICO bitmap")
+	}
+
+	icon := image.NewNRGBA(image.Rect(0, 0, width, height))
+	hasAlpha := false
+	for y := range height {
+		row := pixels[(height-1-y)*stride:]
+		for x := range width {
+			b, g, r, a := row[4*x], row[4*x+1], row[4*x+2], row[4*x+3]
+			hasAlpha = hasAlpha || a != 0
+			icon.SetNRGBA(x, y, color.NRGBA{R: r, G: g, B: b, A: a})
+		}
+	}
+
+	if !hasAlpha {
+		for i := 3; i < len(icon.Pix); i += 4 {
+			icon.Pix[i] = 0xff
+		}
+	}
+	return icon, nil
+}
+
+// dibStride returns the size of a bitmap row, padded to 4 bytes.
+func dibStride(width, depth int) int {
+	return (width*depth + 31) / 32 * 4
+}
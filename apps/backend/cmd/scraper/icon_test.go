@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"slices"
+	"testing"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// buildICO wraps a single image, PNG or headerless BMP, into an ICO file.
+func buildICO(width int, depth int, entry []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(icoMagic)
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	buf.Write([]byte{byte(width), byte(width), 0, 0})
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, uint16(depth))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(entry)))
+	binary.Write(&buf, binary.LittleEndian, uint32(6+16))
+	buf.Write(entry)
+	return buf.Bytes()
+}
+
+// dibHeader returns a BITMAPINFOHEADER as found in ICO files, twice as high
+// as the image.
+func dibHeader(width, height, depth int) []byte {
+	header := make([]byte, 40)
+	binary.LittleEndian.PutUint32(header[0:4], 40)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(width))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(2*height))
+	binary.LittleEndian.PutUint16(header[12:14], 1)
+	binary.LittleEndian.PutUint16(header[14:16], uint16(depth))
+	return header
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func decodeNormalized(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	normalized, err := normalizeIcon(data)
+	if err != nil {
+		t.Fatalf("normalizeIcon() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(normalized))
+	if err != nil {
+		t.Fatalf("normalized icon is not a PNG: %v", err)
+	}
+	if img.Bounds().Dx() != iconSize || img.Bounds().Dy() != iconSize {
+		t.Fatalf("normalized icon is %v, want %dx%d", img.Bounds(), iconSize, iconSize)
+	}
+	return img
+}
+
+func nrgbaAt(img image.Image, x, y int) color.NRGBA {
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+func TestNormalizeIcon_PNG(t *testing.T) {
+	// A wide red image is centered on a transparent background
+	src := image.NewNRGBA(image.Rect(0, 0, 32, 16))
+	for i := range src.Pix {
+		src.Pix[i] = []byte{0xff, 0, 0, 0xff}[i%4]
+	}
+
+	img := decodeNormalized(t, encodePNG(t, src))
+	if got := nrgbaAt(img, 32, 32); got.R != 0xff || got.A != 0xff {
+		t.Errorf("center pixel = %v, want opaque red", got)
+	}
+	if got := nrgbaAt(img, 32, 2); got.A != 0 {
+		t.Errorf("top pixel = %v, want transparent", got)
+	}
+}
+
+func TestNormalizeIcon_ICO(t *testing.T) {
+	t.Run("embedded PNG", func(t *testing.T) {
+		src := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+		for i := range src.Pix {
+			src.Pix[i] = []byte{0, 0xff, 0, 0xff}[i%4]
+		}
+
+		img := decodeNormalized(t, buildICO(16, 32, encodePNG(t, src)))
+		if got := nrgbaAt(img, 10, 10); got.G != 0xff || got.A != 0xff {
+			t.Errorf("pixel = %v, want opaque green", got)
+		}
+	})
+
+	t.Run("32-bit bitmap", func(t *testing.T) {
+		// Bottom-up BGRA rows: the bottom half is opaque blue, the top
+		// half transparent
+		entry := dibHeader(4, 4, 32)
+		for y := range 4 {
+			for range 4 {
+				if y < 2 {
+					entry = append(entry, 0xff, 0, 0, 0xff)
+				} else {
+					entry = append(entry, 0, 0, 0, 0)
+				}
+			}
+		}
+		entry = append(entry, make([]byte, 4*4)...)
+
+		img := decodeNormalized(t, buildICO(4, 32, entry))
+		if got := nrgbaAt(img, 32, 56); got.B != 0xff || got.A != 0xff {
+			t.Errorf("bottom pixel = %v, want opaque blue", got)
+		}
+		if got := nrgbaAt(img, 32, 8); got.A != 0 {
+			t.Errorf("top pixel = %v, want transparent", got)
+		}
+	})
+
+	t.Run("paletted bitmap with mask", func(t *testing.T) {
+		// 1-bit colors, all white, with the left half masked out
+		entry := dibHeader(8, 8, 1)
+		entry = append(entry, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0)
+		for range 8 {
+			entry = append(entry, 0xff, 0, 0, 0)
+		}
+		for range 8 {
+			entry = append(entry, 0xf0, 0, 0, 0)
+		}
+
+		img := decodeNormalized(t, buildICO(8, 1, entry))
+		if got := nrgbaAt(img, 56, 32); got != (color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+			t.Errorf("right pixel = %v, want opaque white", got)
+		}
+		if got := nrgbaAt(img, 8, 32); got.A != 0 {
+			t.Errorf("left pixel = %v, want transparent", got)
+		}
+	})
+}
+
+func TestNormalizeIcon_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"svg", []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`)},
+		{"html", []byte(`<html><body>Not found</body></html>`)},
+		{"truncated ico", append(slices.Clone(icoMagic), 1, 0)},
+		{"ico entry out of bounds", buildICO(16, 32, nil)[:22]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := normalizeIcon(tt.data); err == nil {
+				t.Error("normalizeIcon() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestNormalizeIcon_TooLarge(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, maxIconDimension+1, 1))
+	if _, err := normalizeIcon(encodePNG(t, src)); err == nil {
+		t.Error("normalizeIcon() error = nil, want an error for an oversized icon")
+	}
+}
+
+func TestIconSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   blogs.BlogConfig
+		metadata blogs.BlogMetadata
+		expected []string
+	}{
+		{
+			name:     "individual with GitHub",
+			config:   blogs.BlogConfig{Kind: blogs.Individual, GitHubHref: "https://github.com/octocat"},
+			metadata: blogs.BlogMetadata{FaviconHref: "https://example.com/icon.png"},
+			expected: []string{"https://github.com/octocat.png?size=64", "https://example.com/icon.png", "https://example.com/favicon.ico"},
+		},
+		{
+			name:     "organization",
+			config:   blogs.BlogConfig{Kind: blogs.Organization, GitHubHref: "https://github.com/example"},
+			expected: []string{"https://example.com/favicon.ico"},
+		},
+		{
+			name:     "declared favicon.ico",
+			config:   blogs.BlogConfig{Kind: blogs.Individual, GitHubHref: "https://gitlab.com/octocat"},
+			metadata: blogs.BlogMetadata{FaviconHref: "https://example.com/favicon.ico"},
+			expected: []string{"https://example.com/favicon.ico"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := iconSources(tt.config, "https://example.com/blog/", tt.metadata)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("iconSources() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
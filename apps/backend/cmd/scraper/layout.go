@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/nesco/techblogs/backend/internal/blogs"
+	"golang.org/x/net/html"
+)
+
+// layoutChangeThreshold is the similarity below which the structure around
+// the matched element is considered redesigned.
+const layoutChangeThreshold = 0.6
+
+// layout fingerprints the DOM structure around the element an href selector
+// matched. Redesigns tend to keep selectors matching something while moving
+// them onto the wrong element; comparing fingerprints catches those.
+type layout struct {
+	// Path lists the ancestors of the match, from <html> down to the match
+	// itself, as tag names followed by their sorted classes (e.g. "li.post").
+	Path []string `json:"path"`
+	// Siblings is the number of elements sharing the match's parent.
+	Siblings int `json:"siblings"`
+	// Children lists the tag names of the match's child elements.
+	Children []string `json:"children"`
+}
+
+func layoutFingerprint(match *goquery.Selection) layout {
+	var fp layout
+	if match == nil || match.Length() == 0 {
+		return fp
+	}
+
+	node := match.Get(0)
+	for n := node; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		fp.Path = append(fp.Path, nodeSignature(n))
+	}
+	slices.Reverse(fp.Path)
+
+	if node.Parent != nil {
+		for n := node.Parent.FirstChild; n != nil; n = n.NextSibling {
+			if n.Type == html.ElementNode {
+				fp.Siblings++
+			}
+		}
+	}
+
+	for n := node.FirstChild; n != nil; n = n.NextSibling {
+		if n.Type == html.ElementNode {
+			fp.Children = append(fp.Children, n.Data)
+		}
+	}
+
+	return fp
+}
+
+func nodeSignature(n *html.Node) string {
+	for _, attr := range n.Attr {
+		if attr.Key == "class" {
+			classes := strings.Fields(attr.Val)
+			if len(classes) == 0 {
+				break
+			}
+			slices.Sort(classes)
+			return n.Data + "." + strings.Join(classes, ".")
+		}
+	}
+	return n.Data
+}
+
+// layoutSimilarity scores how alike two fingerprints are, from 0 (unrelated)
+// to 1 (identical). Ancestors are compared from the match upward, since the
+// nearest ones matter the most.
+func layoutSimilarity(a, b layout) float64 {
+	pathA := slices.Clone(a.Path)
+	pathB := slices.Clone(b.Path)
+	slices.Reverse(pathA)
+	slices.Reverse(pathB)
+
+	return 0.6*sequenceSimilarity(pathA, pathB) +
+		0.2*sequenceSimilarity(a.Children, b.Children) +
+		0.2*countSimilarity(a.Siblings, b.Siblings)
+}
+
+// sequenceSimilarity is the share of positions holding the same value.
+func sequenceSimilarity(a, b []string) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	same := 0
+	for i := 0; i < min(len(a), len(b)); i++ {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(longest)
+}
+
+func countSimilarity(a, b int) float64 {
+	if a == b {
+		return 1
+	}
+	return float64(min(a, b)) / float64(max(a, b))
+}
+
+// checkLayout fingerprints the structure around the matched element of a
+// successfully scraped page and compares it with the previous successful
+// scrape, flagging the blog when it changed significantly.
+func checkLayout(ctx context.Context, repo blogs.Store, config blogs.BlogConfig, doc *goquery.Document) {
+	match := hrefMatch(doc, config)
+	if match == nil {
+		return
+	}
+
+	current := layoutFingerprint(match)
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		log.Printf("Error encoding layout of %s: %v\n", config.BlogName, err)
+		return
+	}
+
+	status, err := repo.GetScrapeStatus(ctx, config.BlogName)
+	if err != nil {
+		log.Printf("Error getting scrape status of %s: %v\n", config.BlogName, err)
+		return
+	}
+
+	similarity := 1.0
+	if status != nil && status.LayoutFingerprint != "" {
+		var previous layout
+		if err := json.Unmarshal([]byte(status.LayoutFingerprint), &previous); err == nil {
+			similarity = layoutSimilarity(previous, current)
+		}
+	}
+
+	changed := similarity < layoutChangeThreshold
+	if changed {
+		log.Printf("Warning: layout of %s changed significantly (similarity %.2f), check its selectors\n", config.BlogName, similarity)
+	}
+
+	if err := repo.RecordLayout(ctx, config.BlogName, string(encoded), similarity, changed); err != nil {
+		log.Printf("Error recording layout of %s: %v\n", config.BlogName, err)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// parkingHosts are domains of parking and domain resale services, which
+// parked domains redirect to.
+var parkingHosts = []string{
+	"above.com",
+	"afternic.com",
+	"bodis.com",
+	"dan.com",
+	"hugedomains.com",
+	"parkingcrew.net",
+	"sedo.com",
+	"sedoparking.com",
+	"undeveloped.com",
+}
+
+// parkingMarkers are found in the pages parking services serve on the parked
+// domain itself.
+var parkingMarkers = []string{
+	"this domain is for sale",
+	"this domain may be for sale",
+	"buy this domain",
+	"domain is parked",
+	"parked free, courtesy of",
+	"sedoparking.com",
+	"parkingcrew.net",
+	"window.park",
+}
+
+// maxLinkBodySize bounds how much of a page is read to look for parking
+// markers; they sit near the top of parking pages.
+const maxLinkBodySize = 64 << 10
+
+// runCheckLinks checks the blog, latest article and GitHub links of the
+// cached blogs and stores the outcome.
+func runCheckLinks(args []string) {
+	fs := flag.NewFlagSet("check-links", flag.ExitOnError)
+	blogName := fs.String("blog", "", "only check the links of this blog (default: all blogs)")
+	fs.Parse(args)
+
+	db, repo := openRepository()
+	defer db.Close()
+	ctx := context.Background()
+
+	cached, err := repo.GetAllBlogs(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get blogs: %v", err)
+	}
+
+	client := newHTTPClient()
+
+	checked, broken := 0, 0
+	for _, blog := range cached {
+		if *blogName != "" && blog.BlogName != *blogName {
+			continue
+		}
+
+		for _, link := range []struct {
+			kind blogs.LinkKind
+			href string
+		}{
+			{blogs.LinkBlog, blog.BlogHref},
+			{blogs.LinkArticle, blog.LatestArticleHref},
+			{blogs.LinkGitHub, blog.GitHubHref},
+		} {
+			if link.href == "" {
+				continue
+			}
+
+			check := checkLink(client, link.kind, link.href)
+			check.BlogName = blog.BlogName
+			checked++
+
+			if check.Status != blogs.LinkOK {
+				if check.Status.Broken() {
+					broken++
+				}
+				log.Printf("%s %s link %s: %s %s\n", blog.BlogName, link.kind, link.href, check.Status, check.Detail)
+			}
+
+			if err := repo.RecordLinkCheck(ctx, check); err != nil {
+				log.Printf("Error recording link check for %s: %v\n", blog.BlogName, err)
+			}
+		}
+	}
+
+	log.Printf("Checked %d links, %d broken\n", checked, broken)
+}
+
+// checkLink checks one link with a HEAD request, falling back to GET when
+// HEAD fails since some servers do not support it.
+func checkLink(client *http.Client, kind blogs.LinkKind, link string) blogs.LinkCheck {
+	check := blogs.LinkCheck{Kind: kind, URL: link}
+
+	resp, body, err := requestLink(client, link, kind == blogs.LinkBlog)
+	if err != nil {
+		check.Status, check.Detail = classifyLinkError(err)
+		return check
+	}
+	check.StatusCode = resp.StatusCode
+	final := resp.Request.URL
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		check.Status = blogs.LinkNotFound
+	case parkedHost(final.Hostname()):
+		check.Status = blogs.LinkParked
+		check.Detail = "redirects to " + final.String()
+	case parkedContent(body):
+		check.Status = blogs.LinkParked
+		check.Detail = "parking page"
+	case resp.StatusCode >= 400:
+		check.Status = blogs.LinkError
+		check.Detail = fmt.Sprintf("status %d", resp.StatusCode)
+	default:
+		if detail := softNotFound(client, link, final); detail != "" {
+			check.Status = blogs.LinkSoft404
+			check.Detail = detail
+		} else {
+			check.Status = blogs.LinkOK
+		}
+	}
+
+	return check
+}
+
+// requestLink sends a HEAD request, retried as GET when it fails or is
+// answered with an error status. With readBody, HTML pages answering HEAD
+// are fetched with GET too, for their content. The returned body is only
+// set for GET requests, and truncated to maxLinkBodySize.
+func requestLink(client *http.Client, link string, readBody bool) (*http.Response, []byte, error) {
+	resp, _, err := sendLinkRequest(client, http.MethodHead, link)
+	if err == nil && resp.StatusCode < 400 && !(readBody && isHTML(resp)) {
+		return resp, nil, nil
+	}
+	var blocked *blockedFetchError
+	if errors.As(err, &blocked) {
+		return nil, nil, err
+	}
+	return sendLinkRequest(client, http.MethodGet, link)
+}
+
+func sendLinkRequest(client *http.Client, method string, link string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, link, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := checkScheme(req); err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxLinkBodySize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return resp, body, nil
+}
+
+func isHTML(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/html"
+}
+
+// classifyLinkError tells certificate problems apart from other failures.
+func classifyLinkError(err error) (blogs.LinkStatus, string) {
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) {
+		if invalid.Reason == x509.Expired {
+			return blogs.LinkTLSExpired, invalid.Error()
+		}
+		return blogs.LinkTLSInvalid, invalid.Error()
+	}
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &hostname) {
+		return blogs.LinkTLSInvalid, err.Error()
+	}
+	return blogs.LinkError, err.Error()
+}
+
+func parkedHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, parking := range parkingHosts {
+		if host == parking || strings.HasSuffix(host, "."+parking) {
+			return true
+		}
+	}
+	return false
+}
+
+func parkedContent(body []byte) bool {
+	if len(body) == 0 {
+		return false
+	}
+	lower := bytes.ToLower(body)
+	for _, marker := range parkingMarkers {
+		if bytes.Contains(lower, []byte(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
+// softNotFound detects a missing page answered with a success status, and
+// returns why it is considered missing. Such pages usually redirect to the
+// home page; otherwise, a page that lands where a made-up sibling URL lands
+// is a generic not-found page.
+func softNotFound(client *http.Client, link string, final *url.URL) string {
+	linkURL, err := url.Parse(link)
+	if err != nil || strings.Trim(linkURL.Path, "/") == "" {
+		return ""
+	}
+
+	if strings.Trim(final.Path, "/") == "" && final.RawQuery == "" {
+		return "redirects to the home page"
+	}
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	probeURL := *linkURL
+	probeURL.Path = path.Join(path.Dir(strings.TrimSuffix(linkURL.Path, "/")), "techblogs-missing-"+hex.EncodeToString(suffix))
+	probeURL.RawQuery = ""
+
+	resp, _, err := requestLink(client, probeURL.String(), false)
+	if err != nil || resp.StatusCode >= 300 {
+		return ""
+	}
+	if resp.Request.URL.String() == final.String() {
+		return "lands on the same page as missing URLs (" + final.String() + ")"
+	}
+	return ""
+}
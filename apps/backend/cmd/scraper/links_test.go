@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+func TestCheckLink(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("<html><body>Home</body></html>"))
+	})
+	mux.HandleFunc("/posts/live", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>Live post</body></html>"))
+	})
+	mux.HandleFunc("/posts/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/posts/no-head", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte("<html><body>No HEAD here</body></html>"))
+	})
+	mux.HandleFunc("/posts/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusFound)
+	})
+	mux.HandleFunc("/posts/down", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc("/parked/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><body><h1>This domain is for sale!</h1></body></html>"))
+	})
+	// Every missing page of the catch-all section lands on its not-found page
+	mux.HandleFunc("/catch-all/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/catch-all/not-found" && r.URL.Path != "/catch-all/exists" {
+			http.Redirect(w, r, "/catch-all/not-found", http.StatusFound)
+			return
+		}
+		w.Write([]byte("<html><body>Page</body></html>"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		kind           blogs.LinkKind
+		path           string
+		expectedStatus blogs.LinkStatus
+	}{
+		{"live article", blogs.LinkArticle, "/posts/live", blogs.LinkOK},
+		{"missing article", blogs.LinkArticle, "/posts/missing", blogs.LinkNotFound},
+		{"gone article", blogs.LinkArticle, "/posts/gone", blogs.LinkNotFound},
+		{"head not allowed", blogs.LinkArticle, "/posts/no-head", blogs.LinkOK},
+		{"redirect to home page", blogs.LinkArticle, "/posts/moved", blogs.LinkSoft404},
+		{"server error", blogs.LinkArticle, "/posts/down", blogs.LinkError},
+		{"parked blog", blogs.LinkBlog, "/parked/", blogs.LinkParked},
+		{"catch-all missing page", blogs.LinkArticle, "/catch-all/missing", blogs.LinkSoft404},
+		{"catch-all existing page", blogs.LinkArticle, "/catch-all/exists", blogs.LinkOK},
+		{"home page", blogs.LinkBlog, "/", blogs.LinkOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := checkLink(newHTTPClient(), tt.kind, server.URL+tt.path)
+			if check.Status != tt.expectedStatus {
+				t.Errorf("status = %q, want %q (%+v)", check.Status, tt.expectedStatus, check)
+			}
+		})
+	}
+}
+
+func TestCheckLink_TLS(t *testing.T) {
+	expired := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	expiredCert, expiredRoot := selfSignedCert(t, time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour))
+	expired.TLS = &tls.Config{Certificates: []tls.Certificate{expiredCert}}
+	expired.StartTLS()
+	defer expired.Close()
+
+	untrusted := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer untrusted.Close()
+
+	client := newHTTPClient()
+	roots := x509.NewCertPool()
+	roots.AddCert(expiredRoot)
+	client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: roots}
+
+	if check := checkLink(client, blogs.LinkBlog, expired.URL); check.Status != blogs.LinkTLSExpired {
+		t.Errorf("expired certificate: status = %q, want %q (%s)", check.Status, blogs.LinkTLSExpired, check.Detail)
+	}
+	if check := checkLink(client, blogs.LinkBlog, untrusted.URL); check.Status != blogs.LinkTLSInvalid {
+		t.Errorf("untrusted certificate: status = %q, want %q (%s)", check.Status, blogs.LinkTLSInvalid, check.Detail)
+	}
+}
+
+func TestParkedHost(t *testing.T) {
+	for host, parked := range map[string]bool{
+		"sedoparking.com":     true,
+		"www.hugedomains.com": true,
+		"dan.com":             true,
+		"jordan.com":          false,
+		"example.com":         false,
+	} {
+		if got := parkedHost(host); got != parked {
+			t.Errorf("parkedHost(%q) = %v, want %v", host, got, parked)
+		}
+	}
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 valid between the given
+// times, along with its parsed form to trust it.
+func selfSignedCert(t *testing.T, notBefore, notAfter time.Time) (tls.Certificate, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, cert
+}
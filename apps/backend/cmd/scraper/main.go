@@ -1,143 +1,408 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/nesco/techblogs/backend/internal/blogs"
-	"github.com/nesco/techblogs/backend/internal/database"
 )
 
+const userAgent = "TechBlogs-Scraper/1.0 (+https://github.com/nesco/techblogs)"
+
 func main() {
-	// Initialize database
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "./data/techblogs.db"
+	var err error
+	allowedNetworks, err = allowedNetworksFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to read allowed networks: %v", err)
 	}
 
-	db, err := database.InitDB(dbPath)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "backfill":
+			runBackfill(os.Args[2:])
+		case "reextract":
+			runReextract(os.Args[2:])
+		case "redirects":
+			runRedirects(os.Args[2:])
+		case "check-links":
+			runCheckLinks(os.Args[2:])
+		case "github":
+			runGitHub(os.Args[2:])
+		default:
+			log.Fatalf("Unknown command %q (expected: backfill, reextract, redirects, check-links, github)", os.Args[1])
+		}
+		return
+	}
+
+	runScrape()
+}
+
+// openRepository opens the PostgreSQL database at DATABASE_URL when it is
+// set, and the SQLite file at DB_PATH otherwise. It refuses to run on a
+// schema older than the embedded migrations.
+func openRepository() (*sql.DB, blogs.Store) {
+	opened, err := blogs.OpenFromEnv(false)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		log.Fatal(err)
 	}
-	defer db.Close()
+	if err := opened.CheckVersion(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	return opened.DB, opened.Repo
+}
+
+// scrapeOptions tune a scrape run. They are read from the environment.
+type scrapeOptions struct {
+	// SnapshotKeep is the number of page snapshots kept per blog (SNAPSHOT_KEEP).
+	// Snapshots are disabled when it is 0.
+	SnapshotKeep int
+}
+
+func scrapeOptionsFromEnv() scrapeOptions {
+	var opts scrapeOptions
 
-	repo := blogs.NewRepository(db)
+	if keep := os.Getenv("SNAPSHOT_KEEP"); keep != "" {
+		n, err := strconv.Atoi(keep)
+		if err != nil || n < 0 {
+			log.Fatalf("Invalid SNAPSHOT_KEEP %q: expected a non-negative integer", keep)
+		}
+		opts.SnapshotKeep = n
+	}
+
+	return opts
+}
+
+func runScrape() {
+	opts := scrapeOptionsFromEnv()
+
+	db, repo := openRepository()
+	defer db.Close()
+	ctx := context.Background()
 
 	// Get all blog configurations
-	configs, err := repo.GetAllBlogConfigs()
+	configs, err := repo.GetAllBlogConfigs(ctx)
 	if err != nil {
 		log.Fatalf("Failed to get blog configs: %v", err)
 	}
 
 	log.Printf("Starting scraper for %d blogs...\n", len(configs))
 
+	client := newHTTPClient()
+
 	// Scrape each blog
 	for _, config := range configs {
+		if config.Disabled {
+			log.Printf("Skipping disabled blog %s\n", config.BlogName)
+			continue
+		}
+		lifecycle, lastAttemptAt, err := scrapeHistory(ctx, repo, config)
+		if err != nil {
+			log.Printf("Error getting history of %s: %v\n", config.BlogName, err)
+		} else if !dueForScrape(lifecycle, lastAttemptAt, time.Now()) {
+			log.Printf("Skipping %s blog %s\n", lifecycle, config.BlogName)
+			continue
+		}
+
 		log.Printf("Scraping %s (%s)...\n", config.BlogName, config.BlogHref)
+		scrapeAndStore(ctx, client, repo, config, opts)
+	}
 
-		articleName, articleHref, err := scrapeBlog(config)
-		if err != nil {
+	log.Println("Scraping complete!")
+}
+
+// Minimum time between two scrapes of blogs that stopped publishing. The
+// scraper itself runs daily (see infra/techblogs-scraper.timer).
+const (
+	dormantScrapeInterval = 7 * 24 * time.Hour
+	deadScrapeInterval    = 30 * 24 * time.Hour
+)
+
+// scrapeHistory returns the lifecycle state of a blog and when it was last
+// scraped, if ever.
+func scrapeHistory(ctx context.Context, repo blogs.Store, config blogs.BlogConfig) (blogs.Lifecycle, *time.Time, error) {
+	lifecycle := blogs.LifecycleOf(nil, config.Archived, time.Now())
+	cached, err := repo.GetBlogCache(ctx, config.BlogName)
+	if err != nil {
+		return "", nil, err
+	}
+	if cached != nil {
+		lifecycle = cached.Lifecycle
+	}
+
+	status, err := repo.GetScrapeStatus(ctx, config.BlogName)
+	if err != nil {
+		return "", nil, err
+	}
+	if status == nil {
+		return lifecycle, nil, nil
+	}
+	return lifecycle, &status.LastAttemptAt, nil
+}
+
+// dueForScrape reports whether a blog should be scraped: archived blogs never
+// are, and blogs that stopped publishing are only checked now and then.
+func dueForScrape(lifecycle blogs.Lifecycle, lastAttemptAt *time.Time, now time.Time) bool {
+	var interval time.Duration
+	switch lifecycle {
+	case blogs.Archived:
+		return false
+	case blogs.Dormant:
+		interval = dormantScrapeInterval
+	case blogs.Dead:
+		interval = deadScrapeInterval
+	}
+	return lastAttemptAt == nil || now.Sub(*lastAttemptAt) >= interval
+}
+
+// scrapeAndStore scrapes one blog and records the outcome. Errors are logged
+// so that one broken blog does not stop the run.
+func scrapeAndStore(ctx context.Context, client *http.Client, repo blogs.Store, config blogs.BlogConfig, opts scrapeOptions) {
+	result, err := scrape(client, config)
+
+	if result.Page != nil {
+		config = trackRedirects(ctx, repo, config, result.Page)
+	}
+
+	if opts.SnapshotKeep > 0 && result.Page != nil {
+		snapshot := blogs.PageSnapshot{
+			BlogName:       config.BlogName,
+			PageURL:        result.Page.URL,
+			Content:        result.Page.Body,
+			MatchedElement: matchedElementHTML(result.Page.Doc, config),
+		}
+		if err := repo.SavePageSnapshot(ctx, snapshot, opts.SnapshotKeep); err != nil {
+			log.Printf("Error saving snapshot for %s: %v\n", config.BlogName, err)
+		}
+	}
+
+	if config.Shadow != nil && result.Page != nil {
+		runShadow(ctx, client, repo, config, result, err)
+	}
+
+	if err != nil {
+		var blocked *blockedFetchError
+		if errors.As(err, &blocked) {
+			log.Printf("Blocked fetch for %s: %v\n", config.BlogName, err)
+		} else {
 			log.Printf("Error scraping %s: %v\n", config.BlogName, err)
-			continue
 		}
+		if err := repo.RecordScrapeFailure(ctx, config.BlogName, err.Error()); err != nil {
+			log.Printf("Error recording failure for %s: %v\n", config.BlogName, err)
+		}
+		return
+	}
+
+	// The previous latest article tells whether the one found is new: the
+	// article history of blogs cached before it existed starts empty
+	previous, err := repo.GetBlogCache(ctx, config.BlogName)
+	if err != nil {
+		log.Printf("Error getting cache of %s: %v\n", config.BlogName, err)
+		return
+	}
+
+	// Update cache
+	blogInfo := blogs.BlogInfo{
+		BlogName:          config.BlogName,
+		BlogHref:          config.BlogHref,
+		LatestArticleName: result.ArticleName,
+		LatestArticleHref: result.ArticleHref,
+		Kind:              config.Kind,
+		GitHubHref:        config.GitHubHref,
+	}
 
-		// Update cache
-		blogInfo := blogs.BlogInfo{
-			BlogName:          config.BlogName,
-			BlogHref:          config.BlogHref,
-			LatestArticleName: articleName,
-			LatestArticleHref: articleHref,
-			Kind:              config.Kind,
-			GitHubHref:        config.GitHubHref,
+	if err := repo.UpsertBlogCache(ctx, blogInfo); err != nil {
+		log.Printf("Error updating cache for %s: %v\n", config.BlogName, err)
+		return
+	}
+
+	if result.Page != nil {
+		metadata, err := blogMetadata(client, result.Page)
+		if err != nil {
+			log.Printf("Error reading metadata of %s: %v\n", config.BlogName, err)
+		}
+		if err := repo.UpdateBlogMetadata(ctx, config.BlogName, metadata); err != nil {
+			log.Printf("Error updating metadata for %s: %v\n", config.BlogName, err)
 		}
+		refreshIcon(ctx, client, repo, config, iconSources(config, result.Page.URL, metadata))
+	}
 
-		if err := repo.UpsertBlogCache(blogInfo); err != nil {
-			log.Printf("Error updating cache for %s: %v\n", config.BlogName, err)
-			continue
+	// Keep the article history in sync with the cache
+	if result.ArticleHref != "" {
+		article := blogs.Article{Name: result.ArticleName, Href: result.ArticleHref}
+		inserted, err := repo.InsertArticles(ctx, config.BlogName, []blogs.Article{article})
+		if err != nil {
+			log.Printf("Error recording article for %s: %v\n", config.BlogName, err)
+		} else if inserted > 0 && (previous == nil || previous.LatestArticleHref != result.ArticleHref) {
+			if err := repo.RecordNewArticle(ctx, config.BlogName); err != nil {
+				log.Printf("Error recording new article for %s: %v\n", config.BlogName, err)
+			}
 		}
+	}
 
-		log.Printf("Successfully scraped %s: %s\n", config.BlogName, articleName)
+	if err := repo.RecordScrapeSuccess(ctx, config.BlogName); err != nil {
+		log.Printf("Error recording success for %s: %v\n", config.BlogName, err)
+	} else if result.Page != nil {
+		checkLayout(ctx, repo, config, result.Page.Doc)
 	}
 
-	log.Println("Scraping complete!")
+	log.Printf("Successfully scraped %s: %s\n", config.BlogName, result.ArticleName)
 }
 
-func scrapeBlog(config blogs.BlogConfig) (articleName string, articleHref string, err error) {
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// newHTTPClient returns the client used for every scraper fetch. It refuses
+// internal addresses and non-http(s) schemes, outside allowedNetworks.
+func newHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout:       30 * time.Second,
+		Transport:     guardedTransport(allowedNetworks),
+		CheckRedirect: checkRedirect,
 	}
+}
+
+// maxPageSize bounds how much of a page is read.
+const maxPageSize = 10 << 20
+
+// fetchedPage is a downloaded and parsed HTML page.
+type fetchedPage struct {
+	// URL is where the page was found, after following Redirects.
+	URL        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Doc        *goquery.Document
+	Redirects  []blogs.Redirect
+}
 
-	if config.ArticleHrefSelector == "" {
-		return "", "", nil
+// fetch downloads a resource, failing on any status other than 200 OK.
+func fetch(client *http.Client, resourceURL string, accept string) ([]byte, http.Header, error) {
+	body, resp, err := download(client, resourceURL, accept)
+	if err != nil {
+		return nil, nil, err
 	}
+	return body, resp.Header, nil
+}
 
-	req, err := http.NewRequest("GET", config.BlogHref, nil)
+// download is fetch returning the whole response, whose body has been read
+// and closed. resp.Request is the request that was finally answered.
+func download(client *http.Client, resourceURL string, accept string) ([]byte, *http.Response, error) {
+	req, err := http.NewRequest("GET", resourceURL, nil)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := checkScheme(req); err != nil {
+		return nil, nil, err
 	}
 
 	// Set custom user agent
-	req.Header.Set("User-Agent", "TechBlogs-Scraper/1.0 (+https://github.com/nesco/techblogs)")
+	req.Header.Set("User-Agent", userAgent)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to fetch blog: %w", err)
+		return nil, nil, fmt.Errorf("failed to fetch blog: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("bad status code: %d", resp.StatusCode)
+		return nil, nil, fmt.Errorf("bad status code: %d", resp.StatusCode)
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
 	if err != nil {
-		return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+		return nil, nil, fmt.Errorf("failed to read page: %w", err)
 	}
 
-	// Find the article href using CSS selector
-	hrefSelection := doc.Find(config.ArticleHrefSelector).First()
-	if hrefSelection.Length() == 0 {
-		return "", "", fmt.Errorf("no articles found with href selector: %s", config.ArticleHrefSelector)
+	return body, resp, nil
+}
+
+// fetchPage downloads and parses an HTML page.
+func fetchPage(client *http.Client, pageURL string) (*fetchedPage, error) {
+	body, resp, err := download(client, pageURL, "")
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract article href from the link
-	articleHref, exists := hrefSelection.Attr("href")
-	if !exists {
-		return "", "", fmt.Errorf("article href not found")
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
-	// Make href absolute if it's relative
-	if !strings.HasPrefix(articleHref, "https://") && !strings.HasPrefix(articleHref, "http://") {
-		articleHref = normalizeURL(config.BlogHref, articleHref)
+	return &fetchedPage{
+		URL:        resp.Request.URL.String(),
+		StatusCode: http.StatusOK,
+		Header:     resp.Header,
+		Body:       body,
+		Doc:        doc,
+		Redirects:  redirectChain(resp),
+	}, nil
+}
+
+// fetchDocument downloads and parses an HTML page.
+func fetchDocument(client *http.Client, pageURL string) (*goquery.Document, error) {
+	page, err := fetchPage(client, pageURL)
+	if err != nil {
+		return nil, err
 	}
+	return page.Doc, nil
+}
 
-	// Find the article name using CSS selector
-	nameSelection := doc.Find(config.ArticleNameSelector).First()
-	if nameSelection.Length() == 0 {
-		return "", "", fmt.Errorf("no articles found with name selector: %s", config.ArticleNameSelector)
+// scrapeResult is the outcome of scraping one blog.
+type scrapeResult struct {
+	ArticleName string
+	ArticleHref string
+	// Page is the fetched listing page. It is set even when extraction
+	// failed, and nil when the blog has nothing to extract.
+	Page *fetchedPage
+}
+
+func scrape(client *http.Client, config blogs.BlogConfig) (scrapeResult, error) {
+	if config.ArticleHrefSelector == "" && config.HrefRule == nil && config.Platform == "" && config.FeedHref == "" && config.Script == "" {
+		return scrapeResult{}, nil
+	}
+
+	page, err := fetchPage(client, config.BlogHref)
+	if err != nil {
+		return scrapeResult{}, err
 	}
 
-	// Extract article name by recursively finding the first leaf element with text
-	articleName = nameSelection.Text() // extractTextFromFirstLeaf(nameSelection)
+	result := scrapeResult{Page: page}
+	result.ArticleName, result.ArticleHref, err = extractArticle(client, page, config)
+	return result, err
+}
 
-	// If no text found, try aria-label attribute
-	if articleName == "" {
-		articleName, _ = nameSelection.Attr("aria-label")
+// scrapeBlog fetches a blog with a fresh client and returns its latest article.
+func scrapeBlog(config blogs.BlogConfig) (articleName string, articleHref string, err error) {
+	result, err := scrape(newHTTPClient(), config)
+	if err != nil {
+		return "", "", err
 	}
+	return result.ArticleName, result.ArticleHref, nil
+}
 
-	// Clean up excessive whitespace and newlines
-	articleName = strings.Join(strings.Fields(articleName), " ")
+// articleText returns the whitespace-normalized text of a selection, falling
+// back to its aria-label when it has no text.
+func articleText(selection *goquery.Selection) string {
+	text := selection.Text()
 
-	if articleName == "" {
-		return "", "", fmt.Errorf("article name is empty")
+	// If no text found, try aria-label attribute
+	if text == "" {
+		text, _ = selection.Attr("aria-label")
 	}
 
-	return articleName, articleHref, nil
+	// Clean up excessive whitespace and newlines
+	return strings.Join(strings.Fields(text), " ")
 }
 
 func normalizeURL(baseURL, path string) string {
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// maxDescriptionLength bounds the description kept for a blog, in runes.
+const maxDescriptionLength = 300
+
+// blogMetadata reads the site-level metadata of a blog from its scraped
+// listing page, which already had to be fetched and usually carries the same
+// tags as the rest of the site. When the listing page is not the site root
+// and lacks some of them, they are taken from the root page. The metadata
+// read so far is returned even when fetching the root fails.
+func blogMetadata(client *http.Client, page *fetchedPage) (blogs.BlogMetadata, error) {
+	metadata := pageMetadata(page)
+	if metadata.Description != "" && metadata.SiteName != "" && metadata.Lang != "" &&
+		metadata.FaviconHref != "" && metadata.FeedHref != "" {
+		return metadata, nil
+	}
+
+	rootURL := resolveAgainst(page.URL, "/")
+	if rootURL == page.URL || rootURL == page.URL+"/" {
+		return metadata, nil
+	}
+	root, err := fetchPage(client, rootURL)
+	if err != nil {
+		return metadata, fmt.Errorf("site root: %w", err)
+	}
+
+	rootMetadata := pageMetadata(root)
+	if metadata.Description == "" {
+		metadata.Description = rootMetadata.Description
+	}
+	if metadata.SiteName == "" {
+		metadata.SiteName = rootMetadata.SiteName
+	}
+	if metadata.Lang == "" {
+		metadata.Lang = rootMetadata.Lang
+	}
+	if metadata.FaviconHref == "" {
+		metadata.FaviconHref = rootMetadata.FaviconHref
+	}
+	if metadata.FeedHref == "" {
+		metadata.FeedHref = rootMetadata.FeedHref
+	}
+	return metadata, nil
+}
+
+// pageMetadata reads the site-level metadata of a blog page.
+func pageMetadata(page *fetchedPage) blogs.BlogMetadata {
+	doc := page.Doc
+	metadata := blogs.BlogMetadata{
+		Description: metaContent(doc, `meta[name="description"]`, `meta[property="og:description"]`, `meta[name="twitter:description"]`),
+		SiteName:    metaContent(doc, `meta[property="og:site_name"]`, `meta[name="application-name"]`),
+		Lang:        strings.ToLower(strings.TrimSpace(doc.Find("html").AttrOr("lang", ""))),
+		FaviconHref: faviconHref(page),
+	}
+
+	if runes := []rune(metadata.Description); len(runes) > maxDescriptionLength {
+		metadata.Description = strings.TrimSpace(string(runes[:maxDescriptionLength-1])) + "…"
+	}
+	if feeds := advertisedFeeds(page); len(feeds) > 0 {
+		metadata.FeedHref = feeds[0]
+	}
+
+	return metadata
+}
+
+// metaContent returns the whitespace-normalized content of the first of the
+// given meta tags to have one.
+func metaContent(doc *goquery.Document, selectors ...string) string {
+	for _, selector := range selectors {
+		content := strings.Join(strings.Fields(doc.Find(selector).First().AttrOr("content", "")), " ")
+		if content != "" {
+			return content
+		}
+	}
+	return ""
+}
+
+// faviconHref returns the absolute URL of the icon declared by a page,
+// preferring plain icons over the larger touch icons.
+func faviconHref(page *fetchedPage) string {
+	var icon, touchIcon string
+	page.Doc.Find("link[rel][href]").EachWithBreak(func(_ int, link *goquery.Selection) bool {
+		href := strings.TrimSpace(link.AttrOr("href", ""))
+		if href == "" {
+			return true
+		}
+		for _, rel := range strings.Fields(strings.ToLower(link.AttrOr("rel", ""))) {
+			switch rel {
+			case "icon":
+				icon = href
+				return false
+			case "apple-touch-icon", "apple-touch-icon-precomposed":
+				if touchIcon == "" {
+					touchIcon = href
+				}
+			}
+		}
+		return true
+	})
+
+	if icon == "" {
+		icon = touchIcon
+	}
+	if icon == "" {
+		return ""
+	}
+	return resolveAgainst(page.URL, icon)
+}
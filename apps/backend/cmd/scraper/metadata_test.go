@@ -0,0 +1,131 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+func TestPageMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected blogs.BlogMetadata
+	}{
+		{
+			name: "full metadata",
+			html: `<html lang="en-US"><head>
+				<meta name="description" content="  Notes on   distributed systems ">
+				<meta property="og:site_name" content="Example Engineering">
+				<link rel="apple-touch-icon" href="/touch.png">
+				<link rel="shortcut icon" href="/favicon.ico">
+				<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+			</head><body></body></html>`,
+			expected: blogs.BlogMetadata{
+				Description: "Notes on distributed systems",
+				SiteName:    "Example Engineering",
+				Lang:        "en-us",
+				FaviconHref: "https://example.com/favicon.ico",
+				FeedHref:    "https://example.com/feed.xml",
+			},
+		},
+		{
+			name: "open graph fallbacks",
+			html: `<html><head>
+				<meta property="og:description" content="From Open Graph">
+				<link rel="apple-touch-icon" href="icons/touch.png">
+			</head><body></body></html>`,
+			expected: blogs.BlogMetadata{
+				Description: "From Open Graph",
+				FaviconHref: "https://example.com/blog/icons/touch.png",
+			},
+		},
+		{
+			name:     "no metadata",
+			html:     `<html><body><p>Hello</p></body></html>`,
+			expected: blogs.BlogMetadata{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("failed to parse HTML: %v", err)
+			}
+			page := &fetchedPage{URL: "https://example.com/blog/", Body: []byte(tt.html), Doc: doc}
+
+			if metadata := pageMetadata(page); metadata != tt.expected {
+				t.Errorf("metadata = %+v, want %+v", metadata, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPageMetadata_LongDescription(t *testing.T) {
+	html := `<html><head><meta name="description" content="` + strings.Repeat("é", 500) + `"></head></html>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("failed to parse HTML: %v", err)
+	}
+
+	metadata := pageMetadata(&fetchedPage{URL: "https://example.com/", Doc: doc})
+	if length := len([]rune(metadata.Description)); length != maxDescriptionLength {
+		t.Errorf("description length = %d, want %d", length, maxDescriptionLength)
+	}
+}
+
+func TestBlogMetadata_RootFallback(t *testing.T) {
+	pages := map[string]string{
+		"/blog/": `<html><head>
+			<meta name="description" content="The engineering blog">
+		</head><body></body></html>`,
+		"/": `<html lang="en"><head>
+			<meta name="description" content="The company">
+			<meta property="og:site_name" content="Example">
+			<link rel="icon" href="/icon.png">
+		</head><body></body></html>`,
+	}
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(pages[r.URL.Path]))
+	}))
+	defer server.Close()
+
+	page, err := fetchPage(server.Client(), server.URL+"/blog/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	metadata, err := blogMetadata(server.Client(), page)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Fields of the listing page win over those of the root
+	expected := blogs.BlogMetadata{
+		Description: "The engineering blog",
+		SiteName:    "Example",
+		Lang:        "en",
+		FaviconHref: server.URL + "/icon.png",
+	}
+	if metadata != expected {
+		t.Errorf("metadata = %+v, want %+v", metadata, expected)
+	}
+
+	// A root listing page is not fetched again
+	page, err = fetchPage(server.Client(), server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := blogMetadata(server.Client(), page); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests["/"] != 2 {
+		t.Errorf("root fetched %d times, want 2", requests["/"])
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// platformAdapter reads the latest article of a blog through the API or feed
+// of the engine it runs on, rather than through selectors.
+type platformAdapter interface {
+	latest(client *http.Client, page *fetchedPage) (blogs.Article, error)
+}
+
+var platformAdapters = map[blogs.Platform]platformAdapter{
+	blogs.PlatformFeed:      feedAdapter{},
+	blogs.PlatformWordPress: wordPressAdapter{},
+	blogs.PlatformGhost:     feedAdapter{paths: []string{"rss/"}},
+	blogs.PlatformSubstack:  feedAdapter{paths: []string{"feed"}},
+	blogs.PlatformMedium:    mediumAdapter{},
+	blogs.PlatformHugo:      feedAdapter{paths: []string{"index.xml"}},
+	blogs.PlatformJekyll:    feedAdapter{paths: []string{"feed.xml", "atom.xml"}},
+	blogs.PlatformHashnode:  feedAdapter{paths: []string{"rss.xml"}},
+}
+
+// extractArticle reads the latest article of a fetched listing page, through
+// the blog's extraction script or platform adapter when it has one and its
+// selectors otherwise.
+func extractArticle(client *http.Client, page *fetchedPage, config blogs.BlogConfig) (articleName string, articleHref string, err error) {
+	if config.Script != "" {
+		article, err := runScript(page, config)
+		if err != nil {
+			return "", "", err
+		}
+		articleHref = resolveAgainst(page.URL, article.Href)
+		articleName, err = applyRules(config.Rules, article.Name, articleHref)
+		if err != nil {
+			return "", "", err
+		}
+		return articleName, articleHref, nil
+	}
+
+	platform := config.Platform
+	if platform == "" && config.FeedHref != "" {
+		platform = blogs.PlatformFeed
+	}
+	if platform == blogs.PlatformAuto {
+		platform = detectPlatform(page)
+	}
+
+	if platform == "" {
+		if config.ArticleHrefSelector == "" && config.HrefRule == nil {
+			return "", "", fmt.Errorf("no platform detected and no selector configured")
+		}
+		return extractLatest(page, config)
+	}
+
+	adapter, ok := platformAdapters[platform]
+	if !ok {
+		return "", "", fmt.Errorf("unknown platform %q", platform)
+	}
+	if feed, ok := adapter.(feedAdapter); ok && config.FeedHref != "" {
+		feed.feeds = []string{resolveAgainst(page.URL, config.FeedHref)}
+		adapter = feed
+	}
+
+	article, err := adapter.latest(client, page)
+	if err != nil {
+		return "", "", fmt.Errorf("%s adapter: %w", platform, err)
+	}
+
+	articleName, err = applyRules(config.Rules, article.Name, article.Href)
+	if err != nil {
+		return "", "", err
+	}
+
+	return articleName, article.Href, nil
+}
+
+// detectPlatform guesses the engine of a blog from its generator meta tag,
+// host and well-known markup. It falls back to PlatformFeed when the page
+// advertises a feed, and returns an empty platform otherwise.
+func detectPlatform(page *fetchedPage) blogs.Platform {
+	generator := strings.ToLower(page.Doc.Find(`meta[name="generator"]`).AttrOr("content", ""))
+	for _, platform := range []blogs.Platform{
+		blogs.PlatformWordPress,
+		blogs.PlatformGhost,
+		blogs.PlatformHugo,
+		blogs.PlatformJekyll,
+		blogs.PlatformHashnode,
+		blogs.PlatformSubstack,
+	} {
+		if strings.Contains(generator, string(platform)) {
+			return platform
+		}
+	}
+
+	if u, err := url.Parse(page.URL); err == nil {
+		host := u.Hostname()
+		switch {
+		case host == "medium.com" || strings.HasSuffix(host, ".medium.com"):
+			return blogs.PlatformMedium
+		case strings.HasSuffix(host, ".substack.com"):
+			return blogs.PlatformSubstack
+		case strings.HasSuffix(host, ".hashnode.dev"):
+			return blogs.PlatformHashnode
+		}
+	}
+
+	body := string(page.Body)
+	switch {
+	case page.Doc.Find(`link[rel="https://api.w.org/"]`).Length() > 0 || strings.Contains(body, "/wp-content/"):
+		return blogs.PlatformWordPress
+	case strings.Contains(body, "substackcdn.com"):
+		return blogs.PlatformSubstack
+	case strings.Contains(body, "cdn.hashnode.com"):
+		return blogs.PlatformHashnode
+	case page.Doc.Find(`meta[property="al:android:package"][content="com.medium.reader"]`).Length() > 0:
+		return blogs.PlatformMedium
+	case strings.Contains(body, "/ghost/api/") || strings.Contains(body, "ghost-portal"):
+		return blogs.PlatformGhost
+	}
+
+	if len(advertisedFeeds(page)) > 0 {
+		return blogs.PlatformFeed
+	}
+
+	return ""
+}
+
+// wordPressAdapter reads the latest post from the WordPress REST API.
+type wordPressAdapter struct{}
+
+func (wordPressAdapter) latest(client *http.Client, page *fetchedPage) (blogs.Article, error) {
+	// The API root is advertised in the page head; it is "/wp-json/" unless
+	// pretty permalinks are disabled
+	apiRoot := page.Doc.Find(`link[rel="https://api.w.org/"]`).AttrOr("href", "")
+	if apiRoot == "" {
+		apiRoot = resolveAgainst(page.URL, "/wp-json/")
+	}
+
+	endpoint := strings.TrimSuffix(apiRoot, "/") + "/wp/v2/posts?per_page=1&_fields=link,title"
+	if strings.Contains(apiRoot, "?") {
+		endpoint = apiRoot + "wp/v2/posts&per_page=1&_fields=link,title"
+	}
+
+	body, _, err := fetch(client, endpoint, "application/json")
+	if err != nil {
+		return blogs.Article{}, err
+	}
+
+	var posts []struct {
+		Link  string `json:"link"`
+		Title struct {
+			Rendered string `json:"rendered"`
+		} `json:"title"`
+	}
+	if err := json.Unmarshal(body, &posts); err != nil {
+		return blogs.Article{}, fmt.Errorf("failed to decode posts: %w", err)
+	}
+	if len(posts) == 0 || posts[0].Link == "" {
+		return blogs.Article{}, fmt.Errorf("no posts returned by %s", endpoint)
+	}
+
+	return blogs.Article{Name: cleanTitle(posts[0].Title.Rendered), Href: posts[0].Link}, nil
+}
+
+// mediumAdapter reads Medium feeds, which live under /feed/ followed by the
+// publication or @user path.
+type mediumAdapter struct{}
+
+func (mediumAdapter) latest(client *http.Client, page *fetchedPage) (blogs.Article, error) {
+	paths := []string{"feed"}
+	if u, err := url.Parse(page.URL); err == nil {
+		if first, _, _ := strings.Cut(strings.Trim(u.Path, "/"), "/"); first != "" {
+			paths = []string{"/feed/" + first, "feed"}
+		}
+	}
+	return feedAdapter{paths: paths}.latest(client, page)
+}
+
+// feedAdapter reads the first item of the blog's RSS or Atom feed. The
+// configured feed comes first, then the feeds advertised by the page, then
+// the engine's usual feed paths, relative to the listing page and to the
+// site root.
+type feedAdapter struct {
+	feeds []string
+	paths []string
+}
+
+func (a feedAdapter) latest(client *http.Client, page *fetchedPage) (blogs.Article, error) {
+	candidates := append(slices.Clone(a.feeds), advertisedFeeds(page)...)
+	for _, path := range a.paths {
+		candidates = append(candidates, resolveAgainst(page.URL, path))
+		if !strings.HasPrefix(path, "/") {
+			candidates = append(candidates, resolveAgainst(page.URL, "/"+path))
+		}
+	}
+	if len(candidates) == 0 {
+		return blogs.Article{}, fmt.Errorf("no feed advertised")
+	}
+
+	lastErr := fmt.Errorf("no feed found")
+	seen := make(map[string]bool)
+	for _, feedURL := range candidates {
+		if seen[feedURL] {
+			continue
+		}
+		seen[feedURL] = true
+
+		body, _, err := fetch(client, feedURL, "application/rss+xml, application/atom+xml, application/xml;q=0.9")
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", feedURL, err)
+			continue
+		}
+
+		articles, err := parseFeed(body)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", feedURL, err)
+			continue
+		}
+		if len(articles) == 0 {
+			lastErr = fmt.Errorf("%s: feed has no items", feedURL)
+			continue
+		}
+
+		article := articles[0]
+		article.Href = resolveAgainst(feedURL, article.Href)
+		return article, nil
+	}
+
+	return blogs.Article{}, lastErr
+}
+
+// advertisedFeeds returns the absolute URLs of the RSS and Atom feeds linked
+// from a page head.
+func advertisedFeeds(page *fetchedPage) []string {
+	var feeds []string
+	page.Doc.Find(`link[rel="alternate"][type="application/rss+xml"], link[rel="alternate"][type="application/atom+xml"]`).Each(func(_ int, link *goquery.Selection) {
+		if href := strings.TrimSpace(link.AttrOr("href", "")); href != "" {
+			feeds = append(feeds, resolveAgainst(page.URL, href))
+		}
+	})
+	return feeds
+}
+
+// resolveAgainst resolves a possibly relative reference against a base URL,
+// returning the reference unchanged when either does not parse.
+func resolveAgainst(base, ref string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// cleanTitle turns a title that may hold HTML markup and entities into text.
+func cleanTitle(title string) string {
+	var text strings.Builder
+	inTag := false
+	for _, r := range title {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+		case !inTag:
+			text.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(text.String())), " ")
+}
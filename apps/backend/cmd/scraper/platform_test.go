@@ -0,0 +1,229 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	tests := []struct {
+		name     string
+		pageURL  string
+		html     string
+		expected blogs.Platform
+	}{
+		{
+			name:     "wordpress generator",
+			pageURL:  "https://example.com/",
+			html:     `<html><head><meta name="generator" content="WordPress 6.4.2"></head></html>`,
+			expected: blogs.PlatformWordPress,
+		},
+		{
+			name:     "wordpress api link",
+			pageURL:  "https://example.com/",
+			html:     `<html><head><link rel="https://api.w.org/" href="https://example.com/wp-json/"></head></html>`,
+			expected: blogs.PlatformWordPress,
+		},
+		{
+			name:     "ghost generator",
+			pageURL:  "https://example.com/",
+			html:     `<html><head><meta name="generator" content="Ghost 5.75"></head></html>`,
+			expected: blogs.PlatformGhost,
+		},
+		{
+			name:     "hugo generator",
+			pageURL:  "https://example.com/",
+			html:     `<html><head><meta name="generator" content="Hugo 0.121.1"></head></html>`,
+			expected: blogs.PlatformHugo,
+		},
+		{
+			name:     "jekyll generator",
+			pageURL:  "https://example.com/",
+			html:     `<html><head><meta name="generator" content="Jekyll v4.3.2"></head></html>`,
+			expected: blogs.PlatformJekyll,
+		},
+		{
+			name:     "substack host",
+			pageURL:  "https://someone.substack.com/",
+			html:     `<html></html>`,
+			expected: blogs.PlatformSubstack,
+		},
+		{
+			name:     "substack custom domain",
+			pageURL:  "https://example.com/",
+			html:     `<html><head><link rel="preconnect" href="https://substackcdn.com"></head></html>`,
+			expected: blogs.PlatformSubstack,
+		},
+		{
+			name:     "medium host",
+			pageURL:  "https://medium.com/@someone",
+			html:     `<html></html>`,
+			expected: blogs.PlatformMedium,
+		},
+		{
+			name:     "hashnode host",
+			pageURL:  "https://someone.hashnode.dev/",
+			html:     `<html></html>`,
+			expected: blogs.PlatformHashnode,
+		},
+		{
+			name:     "advertised feed",
+			pageURL:  "https://example.com/",
+			html:     `<html><head><link rel="alternate" type="application/atom+xml" href="/atom.xml"></head></html>`,
+			expected: blogs.PlatformFeed,
+		},
+		{
+			name:     "unknown",
+			pageURL:  "https://example.com/",
+			html:     `<html><body><a href="/post">Post</a></body></html>`,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("failed to parse page: %v", err)
+			}
+			page := &fetchedPage{URL: tt.pageURL, Body: []byte(tt.html), Doc: doc}
+
+			if got := detectPlatform(page); got != tt.expected {
+				t.Errorf("detectPlatform() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestScrape_Platforms(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/wordpress/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><meta name="generator" content="WordPress 6.4.2"></head><body></body></html>`))
+	})
+	mux.HandleFunc("/wp-json/wp/v2/posts", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("per_page") != "1" {
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"link":"https://example.com/hello","title":{"rendered":"Hello &#8211; <em>World</em>"}}]`))
+	})
+	mux.HandleFunc("/hugo/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><meta name="generator" content="Hugo 0.121.1"></head><body></body></html>`))
+	})
+	mux.HandleFunc("/hugo/index.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+			<rss version="2.0"><channel><title>Hugo Blog</title>
+				<item><title>Newest Post</title><link>/hugo/posts/newest/</link></item>
+				<item><title>Older Post</title><link>/hugo/posts/older/</link></item>
+			</channel></rss>`))
+	})
+	mux.HandleFunc("/atom/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><link rel="alternate" type="application/atom+xml" href="feed.atom"></head><body></body></html>`))
+	})
+	mux.HandleFunc("/atom/feed.atom", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+			<feed xmlns="http://www.w3.org/2005/Atom"><title>Atom Blog</title>
+				<entry><title type="html">Atom &amp;amp; Friends</title>
+					<link rel="replies" href="/atom/comments"/>
+					<link rel="alternate" href="https://example.com/atom-post"/>
+				</entry>
+			</feed>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		platform     blogs.Platform
+		feedHref     string
+		expectedName string
+		expectedHref string
+	}{
+		{
+			name:         "wordpress rest api",
+			path:         "/wordpress/",
+			platform:     blogs.PlatformAuto,
+			expectedName: "Hello – World",
+			expectedHref: "https://example.com/hello",
+		},
+		{
+			name:         "hugo feed",
+			path:         "/hugo/",
+			platform:     blogs.PlatformAuto,
+			expectedName: "Newest Post",
+			expectedHref: server.URL + "/hugo/posts/newest/",
+		},
+		{
+			name:         "advertised atom feed",
+			path:         "/atom/",
+			platform:     blogs.PlatformFeed,
+			expectedName: "Atom & Friends",
+			expectedHref: "https://example.com/atom-post",
+		},
+		{
+			name:         "configured feed first",
+			path:         "/atom/",
+			feedHref:     "/hugo/index.xml",
+			expectedName: "Newest Post",
+			expectedHref: server.URL + "/hugo/posts/newest/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := blogs.BlogConfig{
+				BlogName: "Test Blog",
+				BlogHref: server.URL + tt.path,
+				Platform: tt.platform,
+				FeedHref: tt.feedHref,
+			}
+
+			result, err := scrape(server.Client(), config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.ArticleName != tt.expectedName {
+				t.Errorf("name = %q, want %q", result.ArticleName, tt.expectedName)
+			}
+			if result.ArticleHref != tt.expectedHref {
+				t.Errorf("href = %q, want %q", result.ArticleHref, tt.expectedHref)
+			}
+		})
+	}
+}
+
+func TestScrape_AutoWithoutPlatform(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><a class="post" href="/post">Selector Post</a></body></html>`))
+	}))
+	defer server.Close()
+
+	config := blogs.BlogConfig{
+		BlogName: "Test Blog",
+		BlogHref: server.URL,
+		Platform: blogs.PlatformAuto,
+	}
+
+	if _, err := scrape(server.Client(), config); err == nil || !strings.Contains(err.Error(), "no platform detected") {
+		t.Fatalf("expected a detection error, got %v", err)
+	}
+
+	// Selectors remain the fallback when no platform is detected
+	config.ArticleHrefSelector = "a.post"
+	config.ArticleNameSelector = "a.post"
+	result, err := scrape(server.Client(), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ArticleName != "Selector Post" {
+		t.Errorf("name = %q, want %q", result.ArticleName, "Selector Post")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// redirectChain lists the redirects followed to get a response, in order.
+// Each hop holds the URL redirected to and the status that sent it there.
+func redirectChain(resp *http.Response) []blogs.Redirect {
+	var chain []blogs.Redirect
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append(chain, blogs.Redirect{URL: req.URL.String(), StatusCode: req.Response.StatusCode})
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// permanentRedirect reports whether a chain only holds permanent redirects.
+func permanentRedirect(chain []blogs.Redirect) bool {
+	if len(chain) == 0 {
+		return false
+	}
+	for _, redirect := range chain {
+		if redirect.StatusCode != http.StatusMovedPermanently && redirect.StatusCode != http.StatusPermanentRedirect {
+			return false
+		}
+	}
+	return true
+}
+
+// safeMove reports whether a blog can follow a permanent redirect without
+// review: only upgrades from http to https of the same URL qualify.
+func safeMove(oldHref, newHref string) bool {
+	oldURL, err := url.Parse(oldHref)
+	if err != nil {
+		return false
+	}
+	newURL, err := url.Parse(newHref)
+	if err != nil {
+		return false
+	}
+
+	if oldURL.Scheme != "http" || newURL.Scheme != "https" {
+		return false
+	}
+	if !strings.EqualFold(oldURL.Hostname(), newURL.Hostname()) {
+		return false
+	}
+	if (oldURL.Port() != "" && oldURL.Port() != "80") || (newURL.Port() != "" && newURL.Port() != "443") {
+		return false
+	}
+	return strings.TrimSuffix(oldURL.Path, "/") == strings.TrimSuffix(newURL.Path, "/") &&
+		oldURL.RawQuery == newURL.RawQuery
+}
+
+// trackRedirects records the redirects followed to fetch a blog page. When
+// they are all permanent, the blog is moved to where it ended up if the move
+// is safe, and the move is queued for review otherwise. It returns the
+// configuration with the blog href now in effect.
+func trackRedirects(ctx context.Context, repo blogs.Store, config blogs.BlogConfig, page *fetchedPage) blogs.BlogConfig {
+	permanent := permanentRedirect(page.Redirects)
+	if err := repo.RecordRedirects(ctx, config.BlogName, page.URL, page.Redirects, permanent); err != nil {
+		log.Printf("Error recording redirects for %s: %v\n", config.BlogName, err)
+	}
+
+	if !permanent || page.URL == config.BlogHref {
+		return config
+	}
+
+	if safeMove(config.BlogHref, page.URL) {
+		if err := repo.ApplyBlogHrefChange(ctx, config.BlogName, config.BlogHref, page.URL); err != nil {
+			log.Printf("Error moving %s to %s: %v\n", config.BlogName, page.URL, err)
+			return config
+		}
+		log.Printf("Moved %s from %s to %s\n", config.BlogName, config.BlogHref, page.URL)
+		config.BlogHref = page.URL
+		return config
+	}
+
+	queued, err := repo.QueueBlogHrefChange(ctx, config.BlogName, config.BlogHref, page.URL)
+	if err != nil {
+		log.Printf("Error queuing move of %s: %v\n", config.BlogName, err)
+	} else if queued {
+		log.Printf("Warning: %s permanently redirects to %s, queued for review\n", config.BlogName, page.URL)
+	}
+	return config
+}
+
+// runRedirects lists the blog URL changes waiting for review, or approves or
+// rejects one of them.
+func runRedirects(args []string) {
+	fs := flag.NewFlagSet("redirects", flag.ExitOnError)
+	approve := fs.Int64("approve", 0, "move the blog of this pending change to its new URL")
+	reject := fs.Int64("reject", 0, "dismiss this pending change")
+	all := fs.Bool("all", false, "list applied and rejected changes too")
+	fs.Parse(args)
+
+	db, repo := openRepository()
+	defer db.Close()
+	ctx := context.Background()
+
+	if *approve != 0 || *reject != 0 {
+		if *approve != 0 && *reject != 0 {
+			log.Fatalf("Use either --approve or --reject")
+		}
+		id, approved := *approve, true
+		if *reject != 0 {
+			id, approved = *reject, false
+		}
+
+		change, err := repo.ResolveBlogHrefChange(ctx, id, approved)
+		if err != nil {
+			log.Fatalf("Failed to resolve change %d: %v", id, err)
+		}
+		if change == nil {
+			log.Fatalf("No pending change %d", id)
+		}
+		if approved {
+			log.Printf("Moved %s from %s to %s\n", change.BlogName, change.OldHref, change.NewHref)
+		} else {
+			log.Printf("Rejected move of %s to %s\n", change.BlogName, change.NewHref)
+		}
+		return
+	}
+
+	status := blogs.HrefChangePending
+	if *all {
+		status = ""
+	}
+	changes, err := repo.GetBlogHrefChanges(ctx, status)
+	if err != nil {
+		log.Fatalf("Failed to get blog href changes: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tBLOG\tDETECTED AT\tSTATUS\tOLD HREF\tNEW HREF")
+	for _, change := range changes {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			change.ID, change.BlogName, change.DetectedAt.UTC().Format("2006-01-02 15:04"),
+			change.Status, change.OldHref, change.NewHref)
+	}
+	w.Flush()
+}
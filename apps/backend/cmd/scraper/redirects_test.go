@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+func TestScrape_Redirects(t *testing.T) {
+	newSite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><a class="post" href="/posts/latest">Latest Post</a></body></html>`))
+	}))
+	defer newSite.Close()
+
+	oldSite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blog":
+			http.Redirect(w, r, "/blog/", http.StatusMovedPermanently)
+		case "/blog/":
+			http.Redirect(w, r, newSite.URL+"/blog/", http.StatusPermanentRedirect)
+		case "/temporary":
+			http.Redirect(w, r, newSite.URL+"/blog/", http.StatusFound)
+		}
+	}))
+	defer oldSite.Close()
+
+	tests := []struct {
+		name              string
+		path              string
+		expectedChain     []blogs.Redirect
+		expectedPermanent bool
+	}{
+		{
+			name: "permanent redirects",
+			path: "/blog",
+			expectedChain: []blogs.Redirect{
+				{URL: oldSite.URL + "/blog/", StatusCode: http.StatusMovedPermanently},
+				{URL: newSite.URL + "/blog/", StatusCode: http.StatusPermanentRedirect},
+			},
+			expectedPermanent: true,
+		},
+		{
+			name: "temporary redirect",
+			path: "/temporary",
+			expectedChain: []blogs.Redirect{
+				{URL: newSite.URL + "/blog/", StatusCode: http.StatusFound},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := blogs.BlogConfig{
+				BlogName:            "Test Blog",
+				BlogHref:            oldSite.URL + tt.path,
+				ArticleHrefSelector: "a.post",
+				ArticleNameSelector: "a.post",
+			}
+
+			result, err := scrape(newHTTPClient(), config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.Page.URL != newSite.URL+"/blog/" {
+				t.Errorf("final URL = %q, want %q", result.Page.URL, newSite.URL+"/blog/")
+			}
+			// Relative links resolve against the final URL
+			if result.ArticleHref != newSite.URL+"/posts/latest" {
+				t.Errorf("href = %q, want %q", result.ArticleHref, newSite.URL+"/posts/latest")
+			}
+
+			if len(result.Page.Redirects) != len(tt.expectedChain) {
+				t.Fatalf("chain = %+v, want %+v", result.Page.Redirects, tt.expectedChain)
+			}
+			for i, redirect := range result.Page.Redirects {
+				if redirect != tt.expectedChain[i] {
+					t.Errorf("redirect %d = %+v, want %+v", i, redirect, tt.expectedChain[i])
+				}
+			}
+			if permanent := permanentRedirect(result.Page.Redirects); permanent != tt.expectedPermanent {
+				t.Errorf("permanent = %v, want %v", permanent, tt.expectedPermanent)
+			}
+		})
+	}
+}
+
+func TestSafeMove(t *testing.T) {
+	tests := []struct {
+		oldHref string
+		newHref string
+		safe    bool
+	}{
+		{"http://example.com/blog", "https://example.com/blog", true},
+		{"http://example.com/blog", "https://example.com/blog/", true},
+		{"http://Example.com:80/", "https://example.com/", true},
+		{"http://example.com/blog", "https://www.example.com/blog", false},
+		{"http://example.com/blog", "https://example.com/news", false},
+		{"http://example.com/blog?lang=en", "https://example.com/blog", false},
+		{"https://example.com/blog", "https://example.org/blog", false},
+		{"https://example.com/blog", "http://example.com/blog", false},
+		{"http://example.com:8080/", "https://example.com/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.oldHref+" -> "+tt.newHref, func(t *testing.T) {
+			if safe := safeMove(tt.oldHref, tt.newHref); safe != tt.safe {
+				t.Errorf("safeMove = %v, want %v", safe, tt.safe)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"text/tabwriter"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// runReextract re-runs extraction with the current blog configurations
+// against stored page snapshots, so selector fixes can be checked offline.
+func runReextract(args []string) {
+	fs := flag.NewFlagSet("reextract", flag.ExitOnError)
+	blogName := fs.String("blog", "", "only re-extract this blog (default: all blogs)")
+	allSnapshots := fs.Bool("all-snapshots", false, "use every stored snapshot instead of the latest one")
+	fs.Parse(args)
+
+	db, repo := openRepository()
+	defer db.Close()
+	ctx := context.Background()
+
+	var configs []blogs.BlogConfig
+	if *blogName != "" {
+		config, err := repo.GetBlogConfig(ctx, *blogName)
+		if err != nil {
+			log.Fatalf("Failed to get blog config: %v", err)
+		}
+		if config == nil {
+			log.Fatalf("Unknown blog: %s", *blogName)
+		}
+		configs = append(configs, *config)
+	} else {
+		var err error
+		configs, err = repo.GetAllBlogConfigs(ctx)
+		if err != nil {
+			log.Fatalf("Failed to get blog configs: %v", err)
+		}
+	}
+
+	limit := 1
+	if *allSnapshots {
+		limit = 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BLOG\tFETCHED AT\tRESULT\tARTICLE")
+
+	failures := 0
+	for _, config := range configs {
+		snapshots, err := repo.GetPageSnapshots(ctx, config.BlogName, limit)
+		if err != nil {
+			log.Fatalf("Failed to get snapshots for %s: %v", config.BlogName, err)
+		}
+		if len(snapshots) == 0 {
+			if *blogName != "" {
+				fmt.Fprintf(w, "%s\t-\tNO SNAPSHOT\t\n", config.BlogName)
+			}
+			continue
+		}
+
+		for _, snapshot := range snapshots {
+			fetchedAt := snapshot.FetchedAt.UTC().Format("2006-01-02 15:04")
+			name, href, err := reextractSnapshot(snapshot, config)
+			if err != nil {
+				failures++
+				fmt.Fprintf(w, "%s\t%s\tFAIL\t%v\n", config.BlogName, fetchedAt, err)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\tOK\t%s (%s)\n", config.BlogName, fetchedAt, name, href)
+		}
+	}
+	w.Flush()
+
+	if failures > 0 {
+		log.Printf("%d snapshots failed extraction\n", failures)
+		os.Exit(1)
+	}
+}
+
+// reextractSnapshot extracts the latest article of a stored page snapshot
+// with the given configuration.
+func reextractSnapshot(snapshot blogs.PageSnapshot, config blogs.BlogConfig) (articleName string, articleHref string, err error) {
+	if config.Platform != "" {
+		return "", "", fmt.Errorf("platform %q is read from the live site", config.Platform)
+	}
+	if config.FeedHref != "" && config.Script == "" {
+		return "", "", fmt.Errorf("feed %s is read from the live site", config.FeedHref)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(snapshot.Content))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+
+	// Only successful fetches are snapshotted
+	page := &fetchedPage{URL: snapshot.PageURL, StatusCode: http.StatusOK, Body: snapshot.Content, Doc: doc}
+	return extractArticle(nil, page, config)
+}
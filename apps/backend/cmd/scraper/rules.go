@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// errRuleViolation marks extractions rejected by a blog's validation rules.
+var errRuleViolation = errors.New("validation rule failed")
+
+// applyRules cleans up an extracted article name with the blog's title
+// replacements, then checks the result against its validation rules. It
+// returns the cleaned name, or an error wrapping errRuleViolation.
+func applyRules(rules blogs.ValidationRules, name, href string) (string, error) {
+	for _, replacement := range rules.TitleReplacements {
+		re, err := regexp.Compile(replacement.Pattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid title replacement pattern %q: %w", replacement.Pattern, err)
+		}
+		name = re.ReplaceAllString(name, replacement.Replacement)
+	}
+	name = strings.Join(strings.Fields(name), " ")
+
+	if name == "" {
+		return "", fmt.Errorf("%w: article name is empty after cleanup", errRuleViolation)
+	}
+
+	for _, blocked := range rules.TitleBlocklist {
+		if strings.EqualFold(name, strings.TrimSpace(blocked)) {
+			return "", fmt.Errorf("%w: article name %q is blocklisted", errRuleViolation, name)
+		}
+	}
+
+	if rules.HrefPattern != "" {
+		re, err := regexp.Compile(rules.HrefPattern)
+		if err != nil {
+			return "", fmt.Errorf("invalid href pattern %q: %w", rules.HrefPattern, err)
+		}
+		if !re.MatchString(href) {
+			return "", fmt.Errorf("%w: article href %q does not match %q", errRuleViolation, href, rules.HrefPattern)
+		}
+	}
+
+	return name, nil
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/nesco/techblogs/backend/internal/blogs"
 )
 
@@ -242,6 +245,32 @@ func TestScrapeBlog(t *testing.T) {
 	}
 }
 
+func TestScrapeBlog_NestedListing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>
+			<a href="post-1" class="link">First Post</a>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	config := blogs.BlogConfig{
+		BlogName:            "Test Blog",
+		BlogHref:            server.URL + "/blog/",
+		ArticleHrefSelector: "a.link",
+		ArticleNameSelector: "a.link",
+	}
+
+	_, href, err := scrapeBlog(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Relative hrefs keep the path of the listing page
+	if expected := server.URL + "/blog/post-1"; href != expected {
+		t.Errorf("href = %q, want %q", href, expected)
+	}
+}
+
 func TestScrapeBlog_HTTPErrors(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -341,3 +370,342 @@ func TestScrapeBlog_MalformedHTML(t *testing.T) {
 		t.Errorf("href = %q, want %q", href, server.URL+"/post")
 	}
 }
+
+func TestApplyRules(t *testing.T) {
+	rules := blogs.ValidationRules{
+		TitleReplacements: []blogs.TitleReplacement{
+			{Pattern: `(?i)\s*read more\s*$`, Replacement: ""},
+			{Pattern: `\s*\|\s*Example Blog$`, Replacement: ""},
+			{Pattern: `^\w{3} \d{1,2}, \d{4}`, Replacement: ""},
+		},
+		HrefPattern:    `^https://example\.com/posts/`,
+		TitleBlocklist: []string{"Blog", "Home"},
+	}
+
+	tests := []struct {
+		name          string
+		title         string
+		href          string
+		expectedTitle string
+		errorContains string
+	}{
+		{
+			name:          "strips read more suffix",
+			title:         "Scaling Postgres Read more",
+			href:          "https://example.com/posts/scaling",
+			expectedTitle: "Scaling Postgres",
+		},
+		{
+			name:          "strips site name and glued date",
+			title:         "Oct 12, 2025Scaling Postgres | Example Blog",
+			href:          "https://example.com/posts/scaling",
+			expectedTitle: "Scaling Postgres",
+		},
+		{
+			name:          "blocklisted title",
+			title:         "blog",
+			href:          "https://example.com/posts/scaling",
+			errorContains: `article name "blog" is blocklisted`,
+		},
+		{
+			name:          "href not matching pattern",
+			title:         "Scaling Postgres",
+			href:          "https://example.com/about",
+			errorContains: "does not match",
+		},
+		{
+			name:          "title empty after cleanup",
+			title:         "Read more",
+			href:          "https://example.com/posts/scaling",
+			errorContains: "empty after cleanup",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyRules(rules, tt.title, tt.href)
+			if tt.errorContains != "" {
+				if err == nil {
+					t.Fatalf("expected error containing %q, got nil", tt.errorContains)
+				}
+				if !errors.Is(err, errRuleViolation) {
+					t.Errorf("expected a rule violation, got %v", err)
+				}
+				if !strings.Contains(err.Error(), tt.errorContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errorContains, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expectedTitle {
+				t.Errorf("title = %q, want %q", got, tt.expectedTitle)
+			}
+		})
+	}
+}
+
+func TestScrapeBlog_ValidationRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/" class="link">Blog</a></body></html>`))
+	}))
+	defer server.Close()
+
+	config := blogs.BlogConfig{
+		BlogName:            "Test Blog",
+		BlogHref:            server.URL,
+		ArticleHrefSelector: "a.link",
+		ArticleNameSelector: "a.link",
+		Rules:               blogs.ValidationRules{TitleBlocklist: []string{"Blog"}},
+	}
+
+	_, _, err := scrapeBlog(config)
+	if !errors.Is(err, errRuleViolation) {
+		t.Fatalf("expected a rule violation, got %v", err)
+	}
+}
+
+func TestScrapeBlog_ExtractionRules(t *testing.T) {
+	html := `<html><head>
+		<meta property="og:title" content="Meta Title">
+	</head><body>
+		<div class="card pinned" data-url="/pinned" title="Pinned Post">Pinned</div>
+		<div class="card" data-url="/post-2" title="Second Post">Second</div>
+		<button class="open" onclick="window.location='/from-onclick'">Open</button>
+		<ul><li><a href="/xpath-post">XPath Post</a></li></ul>
+	</body></html>`
+
+	tests := []struct {
+		name          string
+		hrefRule      *blogs.ExtractionRule
+		nameRule      *blogs.ExtractionRule
+		expectedName  string
+		expectedHref  string
+		errorContains string
+	}{
+		{
+			name:         "data attribute with index",
+			hrefRule:     &blogs.ExtractionRule{Selector: "div.card", Attribute: "data-url", Index: 1},
+			nameRule:     &blogs.ExtractionRule{Selector: "div.card", Attribute: "title", Index: 1},
+			expectedName: "Second Post",
+			expectedHref: "/post-2",
+		},
+		{
+			name:         "onclick regex capture",
+			hrefRule:     &blogs.ExtractionRule{Selector: "button.open", Attribute: "onclick", Regex: `location='([^']+)'`},
+			nameRule:     &blogs.ExtractionRule{Selector: `meta[property="og:title"]`, Attribute: "content"},
+			expectedName: "Meta Title",
+			expectedHref: "/from-onclick",
+		},
+		{
+			name:         "xpath elements",
+			hrefRule:     &blogs.ExtractionRule{Engine: blogs.EngineXPath, Selector: "//ul/li[1]/a"},
+			nameRule:     &blogs.ExtractionRule{Engine: blogs.EngineXPath, Selector: "//ul/li[1]/a", Attribute: "text"},
+			expectedName: "XPath Post",
+			expectedHref: "/xpath-post",
+		},
+		{
+			name:         "xpath attribute node",
+			hrefRule:     &blogs.ExtractionRule{Engine: blogs.EngineXPath, Selector: "//div[@class='card'][1]/@data-url", Attribute: "text"},
+			nameRule:     &blogs.ExtractionRule{Engine: blogs.EngineXPath, Selector: "//div[@class='card'][1]/@title"},
+			expectedName: "Second Post",
+			expectedHref: "/post-2",
+		},
+		{
+			name:          "index out of range",
+			hrefRule:      &blogs.ExtractionRule{Selector: "div.card", Attribute: "data-url", Index: 5},
+			errorContains: "no match 5 for css selector",
+		},
+		{
+			name:          "regex not matching",
+			hrefRule:      &blogs.ExtractionRule{Selector: "button.open", Attribute: "onclick", Regex: `href='([^']+)'`},
+			errorContains: "does not match",
+		},
+		{
+			name:          "invalid xpath",
+			hrefRule:      &blogs.ExtractionRule{Engine: blogs.EngineXPath, Selector: "//ul["},
+			errorContains: "invalid xpath",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(html))
+			}))
+			defer server.Close()
+
+			config := blogs.BlogConfig{
+				BlogName:            "Test Blog",
+				BlogHref:            server.URL,
+				ArticleNameSelector: "div.card",
+				HrefRule:            tt.hrefRule,
+				NameRule:            tt.nameRule,
+			}
+
+			name, href, err := scrapeBlog(config)
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if name != tt.expectedName {
+				t.Errorf("name = %q, want %q", name, tt.expectedName)
+			}
+			if href != server.URL+tt.expectedHref {
+				t.Errorf("href = %q, want %q", href, server.URL+tt.expectedHref)
+			}
+		})
+	}
+}
+
+func TestReextractSnapshot(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><div class="post"><a href="/post-1">First Post</a></div></body></html>`))
+	}))
+	defer server.Close()
+
+	broken := blogs.BlogConfig{
+		BlogName:            "Test Blog",
+		BlogHref:            server.URL,
+		ArticleHrefSelector: "a.article",
+		ArticleNameSelector: "a.article",
+	}
+
+	// The page is kept even though the selector no longer matches
+	result, err := scrape(server.Client(), broken)
+	if err == nil {
+		t.Fatal("expected an extraction error")
+	}
+	if result.Page == nil {
+		t.Fatal("expected the fetched page in the result")
+	}
+
+	snapshot := blogs.PageSnapshot{
+		BlogName:       broken.BlogName,
+		PageURL:        result.Page.URL,
+		Content:        result.Page.Body,
+		MatchedElement: matchedElementHTML(result.Page.Doc, broken),
+	}
+	if snapshot.MatchedElement != "" {
+		t.Errorf("matched element = %q, want empty", snapshot.MatchedElement)
+	}
+
+	// A fixed selector can be validated against the snapshot offline
+	server.Close()
+	fixed := broken
+	fixed.ArticleHrefSelector = ".post a"
+	fixed.ArticleNameSelector = ".post a"
+
+	name, href, err := reextractSnapshot(snapshot, fixed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "First Post" || href != server.URL+"/post-1" {
+		t.Errorf("got %q (%s), want %q (%s)", name, href, "First Post", server.URL+"/post-1")
+	}
+
+	if got := matchedElementHTML(result.Page.Doc, fixed); got != `<a href="/post-1">First Post</a>` {
+		t.Errorf("matched element = %q", got)
+	}
+}
+
+func TestLayoutSimilarity(t *testing.T) {
+	fingerprint := func(page string) layout {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+		if err != nil {
+			t.Fatalf("failed to parse page: %v", err)
+		}
+		return layoutFingerprint(doc.Find("a.post").First())
+	}
+
+	original := fingerprint(`<html><body><main class="content"><ul class="posts">
+		<li><a class="post" href="/1"><span>One</span></a></li>
+		<li><a class="post" href="/2"><span>Two</span></a></li>
+	</ul></main></body></html>`)
+
+	if got := strings.Join(original.Path, " > "); got != "html > body > main.content > ul.posts > li > a.post" {
+		t.Errorf("path = %q", got)
+	}
+	if original.Siblings != 1 || len(original.Children) != 1 {
+		t.Errorf("siblings = %d, children = %v", original.Siblings, original.Children)
+	}
+
+	tests := []struct {
+		name    string
+		page    string
+		changed bool
+	}{
+		{
+			name: "new article",
+			page: `<html><body><main class="content"><ul class="posts">
+				<li><a class="post" href="/3"><span>Three</span></a></li>
+				<li><a class="post" href="/1"><span>One</span></a></li>
+			</ul></main></body></html>`,
+			changed: false,
+		},
+		{
+			name: "restyled outer container",
+			page: `<html><body><main class="content wide"><ul class="posts">
+				<li><a class="post" href="/1"><span>One</span></a></li>
+			</ul></main></body></html>`,
+			changed: false,
+		},
+		{
+			name: "redesign with selector still matching",
+			page: `<html><body><header><nav class="menu">
+				<a class="post" href="/about">About</a><a href="/">Home</a><a href="/rss">RSS</a>
+			</nav></header></body></html>`,
+			changed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			similarity := layoutSimilarity(original, fingerprint(tt.page))
+			if changed := similarity < layoutChangeThreshold; changed != tt.changed {
+				t.Errorf("similarity = %.2f, changed = %v, want %v", similarity, changed, tt.changed)
+			}
+		})
+	}
+}
+
+func TestDueForScrape(t *testing.T) {
+	now := time.Now()
+	hoursAgo := func(hours int) *time.Time {
+		at := now.Add(-time.Duration(hours) * time.Hour)
+		return &at
+	}
+
+	tests := []struct {
+		name          string
+		lifecycle     blogs.Lifecycle
+		lastAttemptAt *time.Time
+		expected      bool
+	}{
+		{"active", blogs.Active, hoursAgo(12), true},
+		{"never scraped", blogs.Dormant, nil, true},
+		{"dormant, recently scraped", blogs.Dormant, hoursAgo(24), false},
+		{"dormant, scraped a week ago", blogs.Dormant, hoursAgo(7 * 24), true},
+		{"dead, scraped a week ago", blogs.Dead, hoursAgo(7 * 24), false},
+		{"dead, scraped a month ago", blogs.Dead, hoursAgo(30 * 24), true},
+		{"archived", blogs.Archived, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if due := dueForScrape(tt.lifecycle, tt.lastAttemptAt, now); due != tt.expected {
+				t.Errorf("dueForScrape = %v, want %v", due, tt.expected)
+			}
+		})
+	}
+}
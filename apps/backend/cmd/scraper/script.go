@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/htmlquery"
+	"github.com/nesco/techblogs/backend/internal/blogs"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// Limits applied to every extraction script run.
+const (
+	scriptMaxSteps = 5_000_000
+	scriptTimeout  = 2 * time.Second
+)
+
+// runScript runs a blog's Starlark extraction script against a fetched page.
+//
+// The script must define extract(doc, response) and return either a
+// (name, href) tuple or a {"name": ..., "href": ...} dict. It runs without
+// access to the filesystem or network, bounded in steps and time. See
+// scriptElement for what doc exposes; response has url, status, headers and
+// body fields, and the re module offers search and findall.
+func runScript(page *fetchedPage, config blogs.BlogConfig) (blogs.Article, error) {
+	thread := &starlark.Thread{
+		Name: "extract " + config.BlogName,
+		Print: func(_ *starlark.Thread, msg string) {
+			log.Printf("[%s script] %s\n", config.BlogName, msg)
+		},
+		// Scripts cannot load other modules
+		Load: nil,
+	}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+
+	timer := time.AfterFunc(scriptTimeout, func() {
+		thread.Cancel(fmt.Sprintf("timed out after %s", scriptTimeout))
+	})
+	defer timer.Stop()
+
+	predeclared := starlark.StringDict{
+		"re": scriptRegexModule,
+	}
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, config.BlogName+".star", config.Script, predeclared)
+	if err != nil {
+		return blogs.Article{}, fmt.Errorf("script failed: %w", err)
+	}
+
+	extract, ok := globals["extract"].(starlark.Callable)
+	if !ok {
+		return blogs.Article{}, fmt.Errorf("script does not define extract(doc, response)")
+	}
+
+	headers := starlark.NewDict(len(page.Header))
+	for name := range page.Header {
+		headers.SetKey(starlark.String(strings.ToLower(name)), starlark.String(page.Header.Get(name)))
+	}
+	response := starlarkstruct.FromStringDict(starlark.String("response"), starlark.StringDict{
+		"url":     starlark.String(page.URL),
+		"status":  starlark.MakeInt(page.StatusCode),
+		"headers": headers,
+		"body":    starlark.String(page.Body),
+	})
+	doc := scriptElement{selection: page.Doc.Selection}
+
+	result, err := starlark.Call(thread, extract, starlark.Tuple{doc, response}, nil)
+	if err != nil {
+		return blogs.Article{}, fmt.Errorf("script failed: %w", err)
+	}
+
+	return scriptArticle(result)
+}
+
+// scriptArticle converts the value returned by extract into an article.
+func scriptArticle(result starlark.Value) (blogs.Article, error) {
+	var name, href starlark.Value
+	switch v := result.(type) {
+	case starlark.NoneType:
+		return blogs.Article{}, fmt.Errorf("script returned no article")
+	case starlark.Tuple:
+		if v.Len() != 2 {
+			return blogs.Article{}, fmt.Errorf("script returned a tuple of %d values, want (name, href)", v.Len())
+		}
+		name, href = v.Index(0), v.Index(1)
+	case *starlark.Dict:
+		name, _, _ = v.Get(starlark.String("name"))
+		href, _, _ = v.Get(starlark.String("href"))
+	default:
+		return blogs.Article{}, fmt.Errorf("script returned a %s, want (name, href) or a dict", result.Type())
+	}
+
+	nameStr, ok := starlark.AsString(name)
+	if !ok {
+		return blogs.Article{}, fmt.Errorf("script returned a non-string name")
+	}
+	hrefStr, ok := starlark.AsString(href)
+	if !ok || strings.TrimSpace(hrefStr) == "" {
+		return blogs.Article{}, fmt.Errorf("script returned no href")
+	}
+
+	return blogs.Article{
+		Name: strings.Join(strings.Fields(nameStr), " "),
+		Href: strings.TrimSpace(hrefStr),
+	}, nil
+}
+
+// scriptElement exposes an HTML selection to scripts. Attributes: text,
+// html, tag. Methods: find(css) and xpath(expr) return lists of elements,
+// attr(name, default=None) reads an attribute.
+type scriptElement struct {
+	selection *goquery.Selection
+}
+
+var _ starlark.HasAttrs = scriptElement{}
+
+func (e scriptElement) String() string        { return "<element " + goquery.NodeName(e.selection) + ">" }
+func (e scriptElement) Type() string          { return "element" }
+func (e scriptElement) Freeze()               {}
+func (e scriptElement) Truth() starlark.Bool  { return starlark.Bool(e.selection.Length() > 0) }
+func (e scriptElement) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: element") }
+
+func (e scriptElement) AttrNames() []string {
+	return []string{"attr", "find", "html", "tag", "text", "xpath"}
+}
+
+func (e scriptElement) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "text":
+		return starlark.String(articleText(e.selection)), nil
+	case "html":
+		html, err := goquery.OuterHtml(e.selection)
+		if err != nil {
+			return nil, err
+		}
+		return starlark.String(html), nil
+	case "tag":
+		return starlark.String(goquery.NodeName(e.selection)), nil
+	case "attr":
+		return starlark.NewBuiltin("attr", e.attr), nil
+	case "find":
+		return starlark.NewBuiltin("find", e.find), nil
+	case "xpath":
+		return starlark.NewBuiltin("xpath", e.xpath), nil
+	}
+	return nil, nil
+}
+
+func (e scriptElement) attr(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	var fallback starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "default?", &fallback); err != nil {
+		return nil, err
+	}
+	value, exists := e.selection.Attr(name)
+	if !exists {
+		return fallback, nil
+	}
+	return starlark.String(value), nil
+}
+
+func (e scriptElement) find(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var selector string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &selector); err != nil {
+		return nil, err
+	}
+
+	var elements []starlark.Value
+	e.selection.Find(selector).Each(func(_ int, match *goquery.Selection) {
+		elements = append(elements, scriptElement{selection: match})
+	})
+	return starlark.NewList(elements), nil
+}
+
+func (e scriptElement) xpath(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var expr string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &expr); err != nil {
+		return nil, err
+	}
+
+	var elements []starlark.Value
+	for _, node := range e.selection.Nodes {
+		matches, err := htmlquery.QueryAll(node, expr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid xpath %q: %w", b.Name(), expr, err)
+		}
+		for _, match := range matches {
+			elements = append(elements, scriptElement{selection: goquery.NewDocumentFromNode(match).Selection})
+		}
+	}
+	return starlark.NewList(elements), nil
+}
+
+// scriptRegexModule gives scripts Go regular expressions:
+// re.search(pattern, s) returns [match, group1, ...] or None, and
+// re.findall(pattern, s) returns the first group (or whole match) of each match.
+var scriptRegexModule = &starlarkstruct.Module{
+	Name: "re",
+	Members: starlark.StringDict{
+		"search":  starlark.NewBuiltin("re.search", scriptRegexSearch),
+		"findall": starlark.NewBuiltin("re.findall", scriptRegexFindAll),
+	},
+}
+
+func scriptRegexSearch(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &pattern, &s); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	groups := re.FindStringSubmatch(s)
+	if groups == nil {
+		return starlark.None, nil
+	}
+	values := make([]starlark.Value, len(groups))
+	for i, group := range groups {
+		values[i] = starlark.String(group)
+	}
+	return starlark.NewList(values), nil
+}
+
+func scriptRegexFindAll(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var pattern, s string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &pattern, &s); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b.Name(), err)
+	}
+
+	var values []starlark.Value
+	for _, groups := range re.FindAllStringSubmatch(s, -1) {
+		value := groups[0]
+		if len(groups) > 1 {
+			value = groups[1]
+		}
+		values = append(values, starlark.String(value))
+	}
+	return starlark.NewList(values), nil
+}
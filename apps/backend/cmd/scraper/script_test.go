@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+func TestScrape_Script(t *testing.T) {
+	html := `<html><head>
+		<script>window.__DATA__ = {"latest": {"url": "/from-script", "title": "Scripted Post"}};</script>
+	</head><body>
+		<div class="card"><span class="badge">Pinned</span><a href="/pinned">Pinned Post</a></div>
+		<div class="card"><a href="/second">Second Post</a></div>
+	</body></html>`
+
+	tests := []struct {
+		name          string
+		script        string
+		expectedName  string
+		expectedHref  string
+		errorContains string
+	}{
+		{
+			name: "skips pinned card",
+			script: `
+def extract(doc, response):
+    for card in doc.find(".card"):
+        if card.find(".badge"):
+            continue
+        link = card.find("a")[0]
+        return (link.text, link.attr("href"))
+    return None
+`,
+			expectedName: "Second Post",
+			expectedHref: "/second",
+		},
+		{
+			name: "reads inline script with regex",
+			script: `
+def extract(doc, response):
+    data = doc.xpath("//script")[0].text
+    url = re.search('"url": "([^"]+)"', data)[1]
+    title = re.search('"title": "([^"]+)"', data)[1]
+    return {"name": title, "href": url}
+`,
+			expectedName: "Scripted Post",
+			expectedHref: "/from-script",
+		},
+		{
+			name: "uses response",
+			script: `
+def extract(doc, response):
+    if response.status != 200 or "text/html" not in response.headers["content-type"]:
+        fail("unexpected response")
+    return ("Body of %d bytes" % len(response.body), response.url + "/latest")
+`,
+			expectedName: fmt.Sprintf("Body of %d bytes", len(html)),
+			expectedHref: "/latest",
+		},
+		{
+			name: "step limit",
+			script: `
+def extract(doc, response):
+    total = 0
+    for i in range(100000000):
+        total += i
+    return ("never", "/never")
+`,
+			errorContains: "too many steps",
+		},
+		{
+			name:          "load is not allowed",
+			script:        `load("other.star", "helper")`,
+			errorContains: "load not implemented",
+		},
+		{
+			name:          "missing extract function",
+			script:        `x = 1`,
+			errorContains: "does not define extract",
+		},
+		{
+			name: "no article",
+			script: `
+def extract(doc, response):
+    return None
+`,
+			errorContains: "returned no article",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write([]byte(html))
+			}))
+			defer server.Close()
+
+			config := blogs.BlogConfig{
+				BlogName: "Test Blog",
+				BlogHref: server.URL,
+				Script:   tt.script,
+			}
+
+			result, err := scrape(server.Client(), config)
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.ArticleName != tt.expectedName {
+				t.Errorf("name = %q, want %q", result.ArticleName, tt.expectedName)
+			}
+			if result.ArticleHref != server.URL+tt.expectedHref {
+				t.Errorf("href = %q, want %q", result.ArticleHref, server.URL+tt.expectedHref)
+			}
+		})
+	}
+}
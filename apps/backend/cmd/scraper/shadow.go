@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// runShadow extracts the latest article of an already fetched page with the
+// blog's shadow strategy and records how it compares with the live result.
+// The shadow result is never published.
+func runShadow(ctx context.Context, client *http.Client, repo blogs.Store, config blogs.BlogConfig, live scrapeResult, liveErr error) {
+	result := compareShadow(client, config, live, liveErr)
+	if !result.Agreed {
+		log.Printf("Shadow extraction of %s disagrees: live %q (%s) error %q, shadow %q (%s) error %q\n",
+			config.BlogName, result.LiveName, result.LiveHref, result.LiveError,
+			result.ShadowName, result.ShadowHref, result.ShadowError)
+	}
+
+	if err := repo.InsertShadowResult(ctx, result); err != nil {
+		log.Printf("Error recording shadow result for %s: %v\n", config.BlogName, err)
+	}
+}
+
+func compareShadow(client *http.Client, config blogs.BlogConfig, live scrapeResult, liveErr error) blogs.ShadowResult {
+	shadow := config.WithStrategy(*config.Shadow)
+
+	result := blogs.ShadowResult{
+		BlogName: config.BlogName,
+		LiveName: live.ArticleName,
+		LiveHref: live.ArticleHref,
+	}
+	if liveErr != nil {
+		result.LiveError = liveErr.Error()
+	}
+
+	name, href, err := extractArticle(client, live.Page, shadow)
+	if err != nil {
+		result.ShadowError = err.Error()
+	} else {
+		result.ShadowName = name
+		result.ShadowHref = href
+	}
+
+	result.Agreed = shadowAgrees(result)
+	return result
+}
+
+// shadowAgrees reports whether both strategies found the same article. Names
+// are not compared since feeds and listing pages often word titles apart.
+func shadowAgrees(result blogs.ShadowResult) bool {
+	return result.LiveError == "" && result.ShadowError == "" &&
+		result.LiveHref != "" && result.LiveHref == result.ShadowHref
+}
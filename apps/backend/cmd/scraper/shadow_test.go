@@ -0,0 +1,192 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+func TestCompareShadow(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><link rel="alternate" type="application/rss+xml" href="/feed.xml"></head><body>
+			<a class="post" href="/posts/newest">Newest Post</a>
+			<a class="post" href="/posts/older">Older Post</a>
+		</body></html>`))
+	})
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+			<rss version="2.0"><channel><title>Blog</title>
+				<item><title>Newest Post: the feed title</title><link>/posts/newest</link></item>
+			</channel></rss>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		shadow        blogs.ExtractionStrategy
+		expectAgreed  bool
+		expectedHref  string
+		errorContains string
+	}{
+		{
+			name:         "feed agrees with selectors",
+			shadow:       blogs.ExtractionStrategy{Platform: blogs.PlatformFeed},
+			expectAgreed: true,
+			expectedHref: server.URL + "/posts/newest",
+		},
+		{
+			name: "rule picking another article",
+			shadow: blogs.ExtractionStrategy{
+				HrefRule: &blogs.ExtractionRule{Selector: "a.post", Index: 1},
+				NameRule: &blogs.ExtractionRule{Selector: "a.post", Index: 1},
+			},
+			expectedHref: server.URL + "/posts/older",
+		},
+		{
+			name:          "failing shadow",
+			shadow:        blogs.ExtractionStrategy{ArticleHrefSelector: ".missing", ArticleNameSelector: ".missing"},
+			errorContains: "no articles found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := blogs.BlogConfig{
+				BlogName:            "Test Blog",
+				BlogHref:            server.URL + "/",
+				ArticleHrefSelector: "a.post",
+				ArticleNameSelector: "a.post",
+				Shadow:              &tt.shadow,
+			}
+
+			live, err := scrape(server.Client(), config)
+			if err != nil {
+				t.Fatalf("unexpected live error: %v", err)
+			}
+
+			result := compareShadow(server.Client(), config, live, nil)
+			if result.Agreed != tt.expectAgreed {
+				t.Errorf("agreed = %v, want %v (%+v)", result.Agreed, tt.expectAgreed, result)
+			}
+			if result.LiveHref != server.URL+"/posts/newest" {
+				t.Errorf("live href = %q", result.LiveHref)
+			}
+			if result.ShadowHref != tt.expectedHref {
+				t.Errorf("shadow href = %q, want %q", result.ShadowHref, tt.expectedHref)
+			}
+			if tt.errorContains != "" && !strings.Contains(result.ShadowError, tt.errorContains) {
+				t.Errorf("shadow error = %q, want it to contain %q", result.ShadowError, tt.errorContains)
+			}
+		})
+	}
+}
+
+func TestCompareShadow_LiveFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body><a class="post" href="/post">Post</a></body></html>`))
+	}))
+	defer server.Close()
+
+	config := blogs.BlogConfig{
+		BlogName:            "Test Blog",
+		BlogHref:            server.URL + "/",
+		ArticleHrefSelector: ".broken",
+		ArticleNameSelector: ".broken",
+		Shadow:              &blogs.ExtractionStrategy{ArticleHrefSelector: "a.post", ArticleNameSelector: "a.post"},
+	}
+
+	live, err := scrape(server.Client(), config)
+	if err == nil {
+		t.Fatal("expected live error")
+	}
+
+	result := compareShadow(server.Client(), config, live, err)
+	if result.Agreed {
+		t.Error("expected disagreement when the live strategy fails")
+	}
+	if result.LiveError == "" || result.ShadowError != "" {
+		t.Errorf("unexpected errors: live %q, shadow %q", result.LiveError, result.ShadowError)
+	}
+	if result.ShadowName != "Post" {
+		t.Errorf("shadow name = %q, want %q", result.ShadowName, "Post")
+	}
+}
+
+func TestCompareShadow_BlogWithFeed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><body>
+			<a class="post" href="/posts/newest">Newest Post</a>
+			<a class="post" href="/posts/older">Older Post</a>
+		</body></html>`))
+	})
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+			<rss version="2.0"><channel><title>Blog</title>
+				<item><title>Newest Post</title><link>/posts/newest</link></item>
+			</channel></rss>`))
+	})
+	mux.HandleFunc("/other.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+			<rss version="2.0"><channel><title>Blog</title>
+				<item><title>Older Post</title><link>/posts/older</link></item>
+			</channel></rss>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		shadow       blogs.ExtractionStrategy
+		expectedHref string
+	}{
+		{
+			name: "selectors instead of the feed",
+			shadow: blogs.ExtractionStrategy{
+				HrefRule: &blogs.ExtractionRule{Selector: "a.post", Index: 1},
+				NameRule: &blogs.ExtractionRule{Selector: "a.post", Index: 1},
+			},
+			expectedHref: server.URL + "/posts/older",
+		},
+		{
+			name:         "another feed",
+			shadow:       blogs.ExtractionStrategy{FeedHref: server.URL + "/other.xml"},
+			expectedHref: server.URL + "/posts/older",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := blogs.BlogConfig{
+				BlogName: "Test Blog",
+				BlogHref: server.URL + "/",
+				FeedHref: server.URL + "/feed.xml",
+				Shadow:   &tt.shadow,
+			}
+
+			live, err := scrape(server.Client(), config)
+			if err != nil {
+				t.Fatalf("unexpected live error: %v", err)
+			}
+
+			result := compareShadow(server.Client(), config, live, nil)
+			if result.LiveHref != server.URL+"/posts/newest" {
+				t.Errorf("live href = %q", result.LiveHref)
+			}
+			if result.ShadowHref != tt.expectedHref || result.ShadowError != "" {
+				t.Errorf("shadow href = %q, error %q, want %q", result.ShadowHref, result.ShadowError, tt.expectedHref)
+			}
+			if result.Agreed {
+				t.Error("expected the shadow to disagree with the feed")
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// allowedNetworks are exempted from the address checks of scraper fetches.
+// It is empty in production and set from ALLOWED_NETWORKS for local testing.
+var allowedNetworks []netip.Prefix
+
+// blockedNetworks lists the special-purpose ranges netip has no predicate
+// for. Loopback, private, link-local (which covers the 169.254.169.254 cloud
+// metadata endpoint), multicast and unspecified addresses are blocked too.
+var blockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),         // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),     // carrier-grade NAT, Alibaba Cloud metadata
+	netip.MustParsePrefix("192.0.0.0/24"),      // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),     // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),       // reserved, broadcast
+	netip.MustParsePrefix("64:ff9b:1::/48"),    // local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),     // documentation
+	netip.MustParsePrefix("fd00:ec2::254/128"), // AWS IPv6 metadata, also in fc00::/7
+}
+
+// nat64Network embeds IPv4 addresses in its last 4 bytes, which are checked
+// in turn.
+var nat64Network = netip.MustParsePrefix("64:ff9b::/96")
+
+// blockedFetchError reports a fetch refused because it targeted an internal
+// address or a scheme other than http and https.
+type blockedFetchError struct {
+	// Target is the refused URL, or the resolved address for dials.
+	Target string
+	Reason string
+}
+
+func (e *blockedFetchError) Error() string {
+	return fmt.Sprintf("blocked fetch of %s: %s", e.Target, e.Reason)
+}
+
+// allowedNetworksFromEnv parses ALLOWED_NETWORKS, a comma-separated list of
+// CIDR prefixes or addresses.
+func allowedNetworksFromEnv() ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(os.Getenv("ALLOWED_NETWORKS"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ALLOWED_NETWORKS entry %q: %w", field, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ALLOWED_NETWORKS entry %q: %w", field, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// checkAddr returns a blockedFetchError when an address is not publicly
+// routable and not allowed.
+func checkAddr(addr netip.Addr, allowed []netip.Prefix) error {
+	addr = addr.Unmap()
+	for _, prefix := range allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+
+	blocked := func(reason string) error {
+		return &blockedFetchError{Target: addr.String(), Reason: reason}
+	}
+
+	switch {
+	case addr.IsLoopback():
+		return blocked("loopback address")
+	case addr.IsPrivate():
+		return blocked("private address")
+	case addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast(), addr.IsInterfaceLocalMulticast():
+		return blocked("link-local address")
+	case addr.IsMulticast():
+		return blocked("multicast address")
+	case addr.IsUnspecified():
+		return blocked("unspecified address")
+	}
+
+	for _, prefix := range blockedNetworks {
+		if prefix.Contains(addr) {
+			return blocked("reserved address")
+		}
+	}
+
+	if nat64Network.Contains(addr) {
+		raw := addr.As16()
+		return checkAddr(netip.AddrFrom4([4]byte(raw[12:])), allowed)
+	}
+
+	return nil
+}
+
+// checkScheme rejects URLs that are not fetched over http or https.
+func checkScheme(req *http.Request) error {
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return &blockedFetchError{Target: req.URL.String(), Reason: fmt.Sprintf("unsupported scheme %q", req.URL.Scheme)}
+	}
+	return nil
+}
+
+// guardedTransport returns a transport refusing connections to internal
+// addresses. The check runs on the resolved address of every dial, so it
+// covers redirects and DNS names pointing inside the network alike.
+func guardedTransport(allowed []netip.Prefix) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return &blockedFetchError{Target: address, Reason: "unresolved address"}
+			}
+			return checkAddr(addrPort.Addr(), allowed)
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the dial checks apply to the proxy instead of the site
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// checkRedirect applies the scheme check to redirects and keeps the default
+// limit of 10 hops.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if err := checkScheme(req); err != nil {
+		return err
+	}
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after 10 redirects")
+	}
+	return nil
+}
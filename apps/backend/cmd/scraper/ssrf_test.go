@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Test servers listen on loopback
+	allowedNetworks = []netip.Prefix{
+		netip.MustParsePrefix("127.0.0.0/8"),
+		netip.MustParsePrefix("::1/128"),
+	}
+	os.Exit(m.Run())
+}
+
+func TestCheckAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"fe80::1", true},
+		{"fd00:ec2::254", true},
+		{"100.100.100.200", true},
+		{"0.0.0.0", true},
+		{"::", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"64:ff9b::a00:1", true},
+		{"64:ff9b::5db8:d822", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := checkAddr(netip.MustParseAddr(tt.addr), nil)
+			if blocked := err != nil; blocked != tt.blocked {
+				t.Errorf("blocked = %v, want %v (err: %v)", blocked, tt.blocked, err)
+			}
+		})
+	}
+
+	allowed := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	if err := checkAddr(netip.MustParseAddr("10.1.2.3"), allowed); err != nil {
+		t.Errorf("allowed address blocked: %v", err)
+	}
+}
+
+func TestAllowedNetworksFromEnv(t *testing.T) {
+	t.Setenv("ALLOWED_NETWORKS", "127.0.0.1, 10.0.0.0/8,")
+	prefixes, err := allowedNetworksFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prefixes) != 2 || prefixes[0].String() != "127.0.0.1/32" || prefixes[1].String() != "10.0.0.0/8" {
+		t.Errorf("prefixes = %v", prefixes)
+	}
+
+	t.Setenv("ALLOWED_NETWORKS", "localhost")
+	if _, err := allowedNetworksFromEnv(); err == nil {
+		t.Error("expected error for a host name")
+	}
+}
+
+func TestFetch_Blocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/to-file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/to-other-loopback":
+			http.Redirect(w, r, "http://"+strings.Replace(r.Host, "127.0.0.1", "127.0.0.2", 1)+"/", http.StatusFound)
+		default:
+			w.Write([]byte("<html></html>"))
+		}
+	}))
+	defer server.Close()
+
+	guarded := func(allowed ...string) *http.Client {
+		var prefixes []netip.Prefix
+		for _, prefix := range allowed {
+			prefixes = append(prefixes, netip.MustParsePrefix(prefix))
+		}
+		return &http.Client{Transport: guardedTransport(prefixes), CheckRedirect: checkRedirect}
+	}
+
+	tests := []struct {
+		name          string
+		client        *http.Client
+		url           string
+		errorContains string
+	}{
+		{
+			name:          "loopback address",
+			client:        guarded(),
+			url:           server.URL,
+			errorContains: "loopback address",
+		},
+		{
+			name:          "name resolving to loopback",
+			client:        guarded(),
+			url:           strings.Replace(server.URL, "127.0.0.1", "localhost", 1),
+			errorContains: "loopback address",
+		},
+		{
+			name:          "redirect to another address",
+			client:        guarded("127.0.0.1/32"),
+			url:           server.URL + "/to-other-loopback",
+			errorContains: "blocked fetch of 127.0.0.2",
+		},
+		{
+			name:          "redirect to file scheme",
+			client:        guarded("127.0.0.1/32"),
+			url:           server.URL + "/to-file",
+			errorContains: `unsupported scheme "file"`,
+		},
+		{
+			name:          "ftp scheme",
+			client:        guarded(),
+			url:           "ftp://example.com/",
+			errorContains: `unsupported scheme "ftp"`,
+		},
+		{
+			name:   "allowed address",
+			client: guarded("127.0.0.1/32"),
+			url:    server.URL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := fetch(tt.client, tt.url, "")
+			if tt.errorContains == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var blocked *blockedFetchError
+			if !errors.As(err, &blocked) {
+				t.Fatalf("expected a blockedFetchError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.errorContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errorContains, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,523 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+	"github.com/nesco/techblogs/backend/internal/directory"
+)
+
+// runAdmin inspects and edits the blog configurations and the cache in the
+// database:
+//
+//	techblogs admin blogs list|show|add|edit|remove|disable|enable [flags]
+//	techblogs admin cache clear --blog NAME | --all
+//	techblogs admin layout list [--json]
+//	techblogs admin layout clear --blog NAME
+//
+// Changes other than disabling a blog are reverted by the next sync unless
+// they are also made in the directory file.
+func runAdmin(args []string) {
+	if len(args) < 2 {
+		log.Fatalf("Missing admin command (expected: blogs list|show|add|edit|remove|disable|enable, cache clear, layout list|clear)")
+	}
+
+	command := args[0] + " " + args[1]
+	args = args[2:]
+	switch command {
+	case "blogs list":
+		adminListBlogs(args)
+	case "blogs show":
+		adminShowBlog(args)
+	case "blogs add":
+		adminAddBlog(args)
+	case "blogs edit":
+		adminEditBlog(args)
+	case "blogs remove":
+		adminRemoveBlog(args)
+	case "blogs disable":
+		adminSetBlogDisabled(args, true)
+	case "blogs enable":
+		adminSetBlogDisabled(args, false)
+	case "cache clear":
+		adminClearCache(args)
+	case "layout list":
+		adminListLayoutChanges(args)
+	case "layout clear":
+		adminClearLayoutChange(args)
+	default:
+		log.Fatalf("Unknown admin command %q (expected: blogs list|show|add|edit|remove|disable|enable, cache clear, layout list|clear)", command)
+	}
+}
+
+// adminBlog is a blog as printed in JSON: its directory file entry, along
+// with whether it is disabled.
+type adminBlog struct {
+	directory.Blog
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+func newAdminBlog(config blogs.BlogConfig) adminBlog {
+	return adminBlog{Blog: directory.FromConfig(config), Disabled: config.Disabled}
+}
+
+func adminListBlogs(args []string) {
+	fs := flag.NewFlagSet("blogs list", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	fs.Parse(args)
+
+	db, repo := openRepository()
+	defer db.Close()
+
+	configs, err := repo.GetAllBlogConfigs(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to get blog configs: %v", err)
+	}
+
+	if *asJSON {
+		list := make([]adminBlog, 0, len(configs))
+		for _, config := range configs {
+			list = append(list, newAdminBlog(config))
+		}
+		printJSON(list)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKIND\tSTATE\tSTRATEGY\tHREF")
+	for _, config := range configs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", config.BlogName, config.Kind, stateOf(config), strategyOf(config), config.BlogHref)
+	}
+	w.Flush()
+}
+
+func adminShowBlog(args []string) {
+	fs := flag.NewFlagSet("blogs show", flag.ExitOnError)
+	blogName := fs.String("blog", "", "name of the blog to show (required)")
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	fs.Parse(args)
+
+	db, repo := openRepository()
+	defer db.Close()
+	config := getBlogConfig(repo, *blogName)
+
+	if *asJSON {
+		printJSON(newAdminBlog(*config))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, field := range configFields(*config) {
+		fmt.Fprintf(w, "%s\t%s\n", field[0], field[1])
+	}
+	w.Flush()
+	if config.Script != "" {
+		fmt.Printf("\n%s\n", strings.TrimRight(config.Script, "\n"))
+	}
+}
+
+func adminAddBlog(args []string) {
+	fs := flag.NewFlagSet("blogs add", flag.ExitOnError)
+	blogName := fs.String("blog", "", "name of the blog to add (required)")
+	edits := registerConfigFlags(fs)
+	fs.Parse(args)
+	if *blogName == "" {
+		log.Fatalf("Missing --blog")
+	}
+
+	config := blogs.BlogConfig{BlogName: *blogName}
+	edits.apply(&config)
+
+	db, repo := openRepository()
+	defer db.Close()
+	ctx := context.Background()
+
+	existing, err := repo.GetBlogConfig(ctx, config.BlogName)
+	if err != nil {
+		log.Fatalf("Failed to get blog config: %v", err)
+	}
+	if existing != nil {
+		log.Fatalf("Blog %s already exists, use blogs edit", config.BlogName)
+	}
+	lintBlogConfig(repo, config)
+	if err := repo.SyncBlogConfigs(ctx, []blogs.BlogConfig{config}, nil); err != nil {
+		log.Fatalf("Failed to add blog: %v", err)
+	}
+	log.Printf("Added %s\n", config.BlogName)
+	log.Printf("Add it to the directory file too, or the next sync removes it\n")
+}
+
+func adminEditBlog(args []string) {
+	fs := flag.NewFlagSet("blogs edit", flag.ExitOnError)
+	blogName := fs.String("blog", "", "name of the blog to edit (required)")
+	edits := registerConfigFlags(fs)
+	fs.Parse(args)
+	if len(*edits) == 0 {
+		log.Fatalf("Nothing to edit: set at least one field flag")
+	}
+
+	db, repo := openRepository()
+	defer db.Close()
+	current := getBlogConfig(repo, *blogName)
+
+	config := *current
+	edits.apply(&config)
+	lintBlogConfig(repo, config)
+
+	plan := directory.Diff(directory.Directory{Blogs: []blogs.BlogConfig{*current}}, directory.Directory{Blogs: []blogs.BlogConfig{config}})
+	if plan.Empty() {
+		log.Printf("%s is unchanged\n", config.BlogName)
+		return
+	}
+	if err := repo.SyncBlogConfigs(context.Background(), []blogs.BlogConfig{config}, nil); err != nil {
+		log.Fatalf("Failed to edit blog: %v", err)
+	}
+	log.Printf("Updated %s: %s\n", config.BlogName, strings.Join(plan.Updates[0].Fields, ", "))
+	log.Printf("Make the same change in the directory file, or the next sync reverts it\n")
+}
+
+func adminRemoveBlog(args []string) {
+	fs := flag.NewFlagSet("blogs remove", flag.ExitOnError)
+	blogName := fs.String("blog", "", "name of the blog to remove (required)")
+	yes := fs.Bool("yes", false, "confirm the removal")
+	fs.Parse(args)
+
+	db, repo := openRepository()
+	defer db.Close()
+	config := getBlogConfig(repo, *blogName)
+
+	if !*yes {
+		log.Fatalf("Removing %s also deletes its cache, articles and history; run again with --yes to confirm", config.BlogName)
+	}
+	if err := repo.SyncBlogConfigs(context.Background(), nil, []string{config.BlogName}); err != nil {
+		log.Fatalf("Failed to remove blog: %v", err)
+	}
+	log.Printf("Removed %s\n", config.BlogName)
+	log.Printf("Remove it from the directory file too, or the next sync adds it back\n")
+}
+
+// adminSetBlogDisabled disables or enables a blog. Unlike the other edits,
+// this survives syncs.
+func adminSetBlogDisabled(args []string, disabled bool) {
+	name := "blogs enable"
+	if disabled {
+		name = "blogs disable"
+	}
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	blogName := fs.String("blog", "", "name of the blog (required)")
+	fs.Parse(args)
+	if *blogName == "" {
+		log.Fatalf("Missing --blog")
+	}
+
+	db, repo := openRepository()
+	defer db.Close()
+
+	found, err := repo.SetBlogDisabled(context.Background(), *blogName, disabled)
+	if err != nil {
+		log.Fatalf("Failed to update blog: %v", err)
+	}
+	if !found {
+		log.Fatalf("No blog named %s", *blogName)
+	}
+	if disabled {
+		log.Printf("Disabled %s: it is no longer scraped nor shown\n", *blogName)
+	} else {
+		log.Printf("Enabled %s\n", *blogName)
+	}
+}
+
+func adminClearCache(args []string) {
+	fs := flag.NewFlagSet("cache clear", flag.ExitOnError)
+	blogName := fs.String("blog", "", "name of the blog whose cache to clear")
+	all := fs.Bool("all", false, "clear the cache of every blog")
+	fs.Parse(args)
+	if (*blogName == "") == !*all {
+		log.Fatalf("Use either --blog or --all")
+	}
+
+	db, repo := openRepository()
+	defer db.Close()
+
+	cleared, err := repo.ClearBlogCache(context.Background(), *blogName)
+	if err != nil {
+		log.Fatalf("Failed to clear cache: %v", err)
+	}
+	log.Printf("Cleared %d cached blogs; the next scrape fills them again\n", cleared)
+}
+
+// adminListLayoutChanges lists the blogs whose page layout changed
+// significantly, whose selectors probably need checking.
+func adminListLayoutChanges(args []string) {
+	fs := flag.NewFlagSet("layout list", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print JSON instead of a table")
+	fs.Parse(args)
+
+	db, repo := openRepository()
+	defer db.Close()
+
+	statuses, err := repo.GetLayoutChanges(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to get layout changes: %v", err)
+	}
+
+	if *asJSON {
+		type layoutChange struct {
+			BlogName   string    `json:"blogName"`
+			Similarity *float64  `json:"similarity"`
+			ChangedAt  time.Time `json:"changedAt"`
+		}
+		list := make([]layoutChange, 0, len(statuses))
+		for _, status := range statuses {
+			list = append(list, layoutChange{status.BlogName, status.LayoutSimilarity, *status.LayoutChangedAt})
+		}
+		printJSON(list)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIMILARITY\tCHANGED")
+	for _, status := range statuses {
+		similarity := "-"
+		if status.LayoutSimilarity != nil {
+			similarity = strconv.FormatFloat(*status.LayoutSimilarity, 'f', 2, 64)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", status.BlogName, similarity, status.LayoutChangedAt.Format(time.DateTime))
+	}
+	w.Flush()
+}
+
+// adminClearLayoutChange removes the layout change flag of a blog once its
+// selectors were checked or fixed.
+func adminClearLayoutChange(args []string) {
+	fs := flag.NewFlagSet("layout clear", flag.ExitOnError)
+	blogName := fs.String("blog", "", "name of the blog (required)")
+	fs.Parse(args)
+	if *blogName == "" {
+		log.Fatalf("Missing --blog")
+	}
+
+	db, repo := openRepository()
+	defer db.Close()
+
+	cleared, err := repo.ClearLayoutChange(context.Background(), *blogName)
+	if err != nil {
+		log.Fatalf("Failed to clear layout change: %v", err)
+	}
+	if !cleared {
+		log.Fatalf("No layout change flagged for %s", *blogName)
+	}
+	log.Printf("Cleared the layout change of %s; the next scrape records a new baseline\n", *blogName)
+}
+
+// lintBlogConfig exits with the problems of a blog configuration about to
+// be written, checked along with the other blogs for duplicates and against
+// the existing tags. The problems of the other blogs are left to techblogs
+// lint.
+func lintBlogConfig(repo blogs.Store, config blogs.BlogConfig) {
+	d := databaseDirectory(repo)
+	d.Blogs = slices.DeleteFunc(d.Blogs, func(other blogs.BlogConfig) bool { return other.BlogName == config.BlogName })
+	d.Blogs = append(d.Blogs, config)
+	if problems := directory.Lint(d).For(config.BlogName); len(problems) > 0 {
+		log.Fatalf("Invalid blog config:\n%v", problems)
+	}
+}
+
+// getBlogConfig returns the configuration of the named blog, exiting when
+// there is none.
+func getBlogConfig(repo blogs.Store, blogName string) *blogs.BlogConfig {
+	if blogName == "" {
+		log.Fatalf("Missing --blog")
+	}
+	config, err := repo.GetBlogConfig(context.Background(), blogName)
+	if err != nil {
+		log.Fatalf("Failed to get blog config: %v", err)
+	}
+	if config == nil {
+		log.Fatalf("No blog named %s", blogName)
+	}
+	return config
+}
+
+func printJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Fatalf("Failed to encode JSON: %v", err)
+	}
+}
+
+// stateOf describes whether a blog is scraped.
+func stateOf(config blogs.BlogConfig) string {
+	switch {
+	case config.Disabled:
+		return "disabled"
+	case config.Archived:
+		return "archived"
+	}
+	return "enabled"
+}
+
+// strategyOf names how the scraper reads a blog's latest article, in order
+// of precedence.
+func strategyOf(config blogs.BlogConfig) string {
+	switch {
+	case config.Script != "":
+		return "script"
+	case config.Platform != "":
+		return string(config.Platform)
+	case config.FeedHref != "":
+		return string(blogs.PlatformFeed)
+	case config.HrefRule != nil:
+		return "rules"
+	case config.ArticleHrefSelector != "":
+		return "selectors"
+	}
+	return "none"
+}
+
+// configFields lists the set fields of a blog configuration, named as in
+// the directory file, with JSON values for structured ones.
+func configFields(config blogs.BlogConfig) [][2]string {
+	blog := newAdminBlog(config)
+	fields := [][2]string{
+		{"name", blog.Name},
+		{"href", blog.Href},
+		{"kind", string(blog.Kind)},
+		{"state", stateOf(config)},
+		{"strategy", strategyOf(config)},
+	}
+	for _, field := range [][2]string{
+		{"github", blog.GitHub},
+		{"feed", blog.Feed},
+		{"platform", string(blog.Platform)},
+		{"articleHref", blog.ArticleHref},
+		{"articleName", blog.ArticleName},
+		{"nextPage", blog.NextPage},
+		{"pageURLTemplate", blog.PageURLTemplate},
+		{"hrefRule", compactJSON(blog.HrefRule)},
+		{"nameRule", compactJSON(blog.NameRule)},
+		{"rules", compactJSON(blog.Rules)},
+		{"shadow", compactJSON(blog.Shadow)},
+		{"tags", strings.Join(blog.Tags, ", ")},
+	} {
+		if field[1] != "" {
+			fields = append(fields, field)
+		}
+	}
+	if config.Script != "" {
+		fields = append(fields, [2]string{"script", fmt.Sprintf("%d lines, below", strings.Count(strings.TrimRight(config.Script, "\n"), "\n")+1)})
+	}
+	return fields
+}
+
+// compactJSON encodes a structured field, or returns "" when it is unset.
+func compactJSON[T any](value *T) string {
+	if value == nil {
+		return ""
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return string(encoded)
+}
+
+// configEdits are the changes to a blog configuration asked for by flags.
+type configEdits []func(config *blogs.BlogConfig)
+
+func (e configEdits) apply(config *blogs.BlogConfig) {
+	for _, edit := range e {
+		edit(config)
+	}
+}
+
+// registerConfigFlags registers a flag per field of a blog configuration,
+// named after the field of the directory file, and returns the edits the
+// given flags ask for. Structured fields take JSON, and an empty value clears
+// them.
+func registerConfigFlags(fs *flag.FlagSet) *configEdits {
+	edits := &configEdits{}
+	set := func(name, usage string, edit func(config *blogs.BlogConfig, value string)) {
+		fs.Func(name, usage, func(value string) error {
+			*edits = append(*edits, func(config *blogs.BlogConfig) { edit(config, value) })
+			return nil
+		})
+	}
+
+	set("href", "blog URL", func(c *blogs.BlogConfig, v string) { c.BlogHref = v })
+	set("kind", "individual or organization", func(c *blogs.BlogConfig, v string) { c.Kind = blogs.Kind(v) })
+	set("github", "GitHub profile or organization URL", func(c *blogs.BlogConfig, v string) { c.GitHubHref = v })
+	set("feed", "RSS or Atom feed URL", func(c *blogs.BlogConfig, v string) { c.FeedHref = v })
+	set("platform", "blog platform, e.g. wordpress or auto", func(c *blogs.BlogConfig, v string) { c.Platform = blogs.Platform(v) })
+	set("article-href", "CSS selector of the latest article link", func(c *blogs.BlogConfig, v string) { c.ArticleHrefSelector = v })
+	set("article-name", "CSS selector of the latest article title", func(c *blogs.BlogConfig, v string) { c.ArticleNameSelector = v })
+	set("next-page", "CSS selector of the older posts link", func(c *blogs.BlogConfig, v string) { c.NextPageSelector = v })
+	set("page-url-template", "listing page URL template, with {page}", func(c *blogs.BlogConfig, v string) { c.PageURLTemplate = v })
+
+	setJSON(fs, edits, "href-rule", "article link extraction rule, as JSON", func(c *blogs.BlogConfig) **blogs.ExtractionRule { return &c.HrefRule })
+	setJSON(fs, edits, "name-rule", "article title extraction rule, as JSON", func(c *blogs.BlogConfig) **blogs.ExtractionRule { return &c.NameRule })
+	setJSON(fs, edits, "rules", "validation rules, as JSON", func(c *blogs.BlogConfig) *blogs.ValidationRules { return &c.Rules })
+	setJSON(fs, edits, "shadow", "shadow extraction strategy, as JSON", func(c *blogs.BlogConfig) **blogs.ExtractionStrategy { return &c.Shadow })
+
+	fs.Func("script-file", "file holding the extraction script; empty to remove it", func(path string) error {
+		script := ""
+		if path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			script = string(data)
+		}
+		*edits = append(*edits, func(config *blogs.BlogConfig) { config.Script = script })
+		return nil
+	})
+	fs.Func("tags", "comma-separated tags, which must exist; empty to remove them", func(value string) error {
+		var tags []string
+		for tag := range strings.SplitSeq(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		slices.Sort(tags)
+		*edits = append(*edits, func(config *blogs.BlogConfig) { config.Tags = tags })
+		return nil
+	})
+	fs.Func("archived", "true to archive the blog, false to restore it", func(value string) error {
+		archived, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*edits = append(*edits, func(config *blogs.BlogConfig) { config.Archived = archived })
+		return nil
+	})
+
+	return edits
+}
+
+// setJSON registers a flag decoding JSON into a field, refusing unknown
+// fields. The field is cleared by an empty value.
+func setJSON[T any](fs *flag.FlagSet, edits *configEdits, name, usage string, field func(config *blogs.BlogConfig) *T) {
+	fs.Func(name, usage, func(value string) error {
+		var decoded T
+		if value != "" {
+			decoder := json.NewDecoder(strings.NewReader(value))
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&decoded); err != nil {
+				return fmt.Errorf("invalid JSON: %w", err)
+			}
+		}
+		*edits = append(*edits, func(config *blogs.BlogConfig) { *field(config) = decoded })
+		return nil
+	})
+}
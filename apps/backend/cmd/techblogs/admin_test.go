@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+func TestRegisterConfigFlags(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "extract.star")
+	if err := os.WriteFile(script, []byte("def extract(doc, response):\n    return None\n"), 0o644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	fs := flag.NewFlagSet("blogs edit", flag.ContinueOnError)
+	edits := registerConfigFlags(fs)
+	err := fs.Parse([]string{
+		"--href", "https://new.example/",
+		"--feed", "https://new.example/feed.xml",
+		"--href-rule", `{"engine": "xpath", "selector": "//a"}`,
+		"--name-rule", "",
+		"--rules", `{"titleBlocklist": ["Blog"]}`,
+		"--script-file", script,
+		"--archived", "true",
+		"--tags", "web, databases,",
+	})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	config := blogs.BlogConfig{
+		BlogName:            "Alpha",
+		BlogHref:            "https://alpha.example/",
+		Kind:                blogs.Individual,
+		ArticleHrefSelector: "article a",
+		NameRule:            &blogs.ExtractionRule{Selector: "h2"},
+		Disabled:            true,
+	}
+	edits.apply(&config)
+
+	want := blogs.BlogConfig{
+		BlogName:            "Alpha",
+		BlogHref:            "https://new.example/",
+		Kind:                blogs.Individual,
+		ArticleHrefSelector: "article a",
+		FeedHref:            "https://new.example/feed.xml",
+		HrefRule:            &blogs.ExtractionRule{Engine: blogs.EngineXPath, Selector: "//a"},
+		Rules:               blogs.ValidationRules{TitleBlocklist: []string{"Blog"}},
+		Script:              "def extract(doc, response):\n    return None\n",
+		Archived:            true,
+		Disabled:            true,
+		Tags:                []string{"databases", "web"},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("edited config = %+v, want %+v", config, want)
+	}
+}
+
+func TestRegisterConfigFlags_Invalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"--href-rule", `{"selektor": "a"}`},
+		{"--rules", "not json"},
+		{"--archived", "maybe"},
+		{"--script-file", filepath.Join(t.TempDir(), "missing.star")},
+	} {
+		fs := flag.NewFlagSet("blogs edit", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		registerConfigFlags(fs)
+		if err := fs.Parse(args); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+func TestStrategyOf(t *testing.T) {
+	tests := []struct {
+		config blogs.BlogConfig
+		want   string
+	}{
+		{blogs.BlogConfig{}, "none"},
+		{blogs.BlogConfig{ArticleHrefSelector: "a"}, "selectors"},
+		{blogs.BlogConfig{ArticleHrefSelector: "a", HrefRule: &blogs.ExtractionRule{Selector: "a"}}, "rules"},
+		{blogs.BlogConfig{ArticleHrefSelector: "a", FeedHref: "https://a.example/feed"}, "feed"},
+		{blogs.BlogConfig{FeedHref: "https://a.example/feed", Platform: blogs.PlatformGhost}, "ghost"},
+		{blogs.BlogConfig{Platform: blogs.PlatformGhost, Script: "def extract(doc, response): pass"}, "script"},
+	}
+	for _, tt := range tests {
+		if got := strategyOf(tt.config); got != tt.want {
+			t.Errorf("strategyOf(%+v) = %s, want %s", tt.config, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+	"github.com/nesco/techblogs/backend/internal/directory"
+)
+
+// runLint checks the blog configurations in the database, or in a directory
+// file with --file, printing every problem found. It exits with status 1 if
+// there is any.
+func runLint(args []string) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	file := fs.String("file", "", "directory file to lint instead of the database")
+	fs.Parse(args)
+
+	d := lintedDirectory(*file)
+	problems := directory.Lint(d)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		log.Printf("Found %d problems in %d tags and %d blogs\n", len(problems), len(d.Tags), len(d.Blogs))
+		os.Exit(1)
+	}
+	log.Printf("No problems in %d tags and %d blogs\n", len(d.Tags), len(d.Blogs))
+}
+
+// lintedDirectory reads the tags and configurations to lint, from the
+// directory file at path or from the database when path is empty.
+func lintedDirectory(path string) directory.Directory {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read directory file: %v", err)
+		}
+		d, err := directory.Decode(data)
+		if err != nil {
+			log.Fatalf("Failed to read directory file: %s: %v", path, err)
+		}
+		return d
+	}
+
+	db, repo := openRepository()
+	defer db.Close()
+	return databaseDirectory(repo)
+}
+
+// databaseDirectory reads the tags and blog configurations of the database.
+func databaseDirectory(repo blogs.Store) directory.Directory {
+	ctx := context.Background()
+	tags, err := repo.GetTags(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get tags: %v", err)
+	}
+	configs, err := repo.GetAllBlogConfigs(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get blog configs: %v", err)
+	}
+	return directory.Directory{Tags: tags, Blogs: configs}
+}
@@ -0,0 +1,42 @@
+// Command techblogs manages the blogs: techblogs sync applies the directory
+// file to blog_configs, techblogs admin inspects and edits them in place, and
+// techblogs lint checks them.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalf("Missing command (expected: sync, admin, lint)")
+	}
+	switch os.Args[1] {
+	case "sync":
+		runSync(os.Args[2:])
+	case "admin":
+		runAdmin(os.Args[2:])
+	case "lint":
+		runLint(os.Args[2:])
+	default:
+		log.Fatalf("Unknown command %q (expected: sync, admin, lint)", os.Args[1])
+	}
+}
+
+// openRepository opens the database the same way the scraper does, from
+// DATABASE_URL or DB_PATH.
+func openRepository() (*sql.DB, blogs.Store) {
+	opened, err := blogs.OpenFromEnv(false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := opened.CheckVersion(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	return opened.DB, opened.Repo
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nesco/techblogs/backend/internal/directory"
+)
+
+// runSync brings tags and blog_configs in line with the directory file, in a
+// single transaction. Tags and blogs missing from the file are deleted, blogs
+// along with their cache, articles and history.
+func runSync(args []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	file := fs.String("file", "blogs.yaml", "directory file listing the blogs")
+	dryRun := fs.Bool("dry-run", false, "print the changes without applying them")
+	fs.Parse(args)
+
+	desired, err := directory.Load(*file)
+	if err != nil {
+		log.Fatalf("Failed to load directory: %v", err)
+	}
+
+	db, repo := openRepository()
+	defer db.Close()
+
+	plan := directory.Diff(databaseDirectory(repo), desired)
+	if plan.Empty() {
+		fmt.Println("blog_configs and tags are up to date")
+		return
+	}
+	plan.Print(os.Stdout)
+	if *dryRun {
+		return
+	}
+
+	if err := plan.Apply(context.Background(), repo); err != nil {
+		log.Fatalf("Failed to sync directory: %v", err)
+	}
+	log.Printf("Synced %d inserts, %d updates and %d deletes\n", len(plan.Inserts), len(plan.Updates), len(plan.Deletes))
+	log.Printf("Synced %d tag inserts, %d updates and %d deletes\n", len(plan.TagInserts), len(plan.TagUpdates), len(plan.TagDeletes))
+}
@@ -0,0 +1,87 @@
+package blogs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Lifecycle tells whether a blog still publishes.
+type Lifecycle string
+
+const (
+	// Active blogs published a new article within DormantAfter.
+	Active Lifecycle = "active"
+	// Dormant blogs have not published for DormantAfter, and are scraped
+	// less often.
+	Dormant Lifecycle = "dormant"
+	// Dead blogs have not published for DeadAfter.
+	Dead Lifecycle = "dead"
+	// Archived blogs are kept on the site on purpose but no longer scraped.
+	Archived Lifecycle = "archived"
+)
+
+var Lifecycles = []Lifecycle{Active, Dormant, Dead, Archived}
+
+// ShownLifecycles are the states of the blogs listed by default, on the home
+// page as in the API: the ones still publishing, even if rarely.
+var ShownLifecycles = []Lifecycle{Active, Dormant}
+
+// Time without a new article after which a blog becomes dormant, then dead.
+const (
+	DormantAfter = 180 * 24 * time.Hour
+	DeadAfter    = 2 * 365 * 24 * time.Hour
+)
+
+// LifecycleOf computes the lifecycle state of a blog from when it last
+// published an article never seen before. Blogs without any are active,
+// since they have just been added.
+func LifecycleOf(lastNewArticleAt *time.Time, archived bool, now time.Time) Lifecycle {
+	switch {
+	case archived:
+		return Archived
+	case lastNewArticleAt == nil:
+		return Active
+	case now.Sub(*lastNewArticleAt) >= DeadAfter:
+		return Dead
+	case now.Sub(*lastNewArticleAt) >= DormantAfter:
+		return Dormant
+	default:
+		return Active
+	}
+}
+
+// ParseLifecycles reads a comma-separated list of lifecycle states to show
+// on top of ShownLifecycles, as given in ?include=. "all" includes every
+// state.
+func ParseLifecycles(include string) ([]Lifecycle, error) {
+	lifecycles := slices.Clone(ShownLifecycles)
+	for _, name := range strings.Split(include, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+			continue
+		case name == "all":
+			return Lifecycles, nil
+		case slices.Contains(Lifecycles, Lifecycle(name)):
+			if !slices.Contains(lifecycles, Lifecycle(name)) {
+				lifecycles = append(lifecycles, Lifecycle(name))
+			}
+		default:
+			return nil, fmt.Errorf("unknown lifecycle state %q", name)
+		}
+	}
+	return lifecycles, nil
+}
+
+// FilterByLifecycle keeps the blogs in one of the given lifecycle states.
+func FilterByLifecycle(items []BlogInfo, lifecycles ...Lifecycle) []BlogInfo {
+	var filtered []BlogInfo
+	for _, item := range items {
+		if slices.Contains(lifecycles, item.Lifecycle) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
@@ -1,6 +1,13 @@
 // Package blogs provides domain models and data access for tech blog management.
 package blogs
 
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
 type Kind string
 
 const (
@@ -20,6 +27,59 @@ type BlogInfo struct {
 	LatestArticleName string `json:"latestArticleName"`
 	Kind              Kind   `json:"kind"`
 	GitHubHref        string `json:"githubHref"`
+	// BrokenLinks lists the links of the blog found broken by the latest
+	// link check.
+	BrokenLinks []LinkKind `json:"brokenLinks,omitempty"`
+	Lifecycle   Lifecycle  `json:"lifecycle"`
+	// LastNewArticleAt is when an article never seen before last appeared.
+	LastNewArticleAt *time.Time `json:"lastNewArticleAt,omitempty"`
+	BlogMetadata
+	// IconHref is the path of the blog icon served by the API, versioned by
+	// its content. It is empty until an icon was downloaded.
+	IconHref string `json:"iconHref,omitempty"`
+	// GitHub is the GitHub profile of individuals, once enriched.
+	GitHub *GitHubProfile `json:"github,omitempty"`
+	// Tags are the names of the tags of the blog, sorted.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// BlogMetadata describes a blog as its own page does.
+type BlogMetadata struct {
+	Description string `json:"description,omitempty"`
+	SiteName    string `json:"siteName,omitempty"`
+	// Lang is the lower-cased language tag of the page, e.g. "en-us".
+	Lang        string `json:"lang,omitempty"`
+	FaviconHref string `json:"faviconHref,omitempty"`
+	FeedHref    string `json:"feedHref,omitempty"`
+}
+
+// FilterByLang keeps the blogs written in the given language. A primary
+// language such as "en" also matches its regional variants ("en-us").
+func FilterByLang(items []BlogInfo, lang string) []BlogInfo {
+	lang = strings.ToLower(lang)
+	var filtered []BlogInfo
+	for _, item := range items {
+		if item.Lang == lang || strings.HasPrefix(item.Lang, lang+"-") {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// FilterByTag keeps the blogs tagged with the given tag.
+func FilterByTag(items []BlogInfo, tag string) []BlogInfo {
+	var filtered []BlogInfo
+	for _, item := range items {
+		if slices.Contains(item.Tags, tag) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// LinkBroken reports whether the link of the given kind was found broken.
+func (b BlogInfo) LinkBroken(kind LinkKind) bool {
+	return slices.Contains(b.BrokenLinks, kind)
 }
 
 type BlogConfig struct {
@@ -29,4 +89,326 @@ type BlogConfig struct {
 	ArticleHrefSelector string
 	ArticleNameSelector string
 	GitHubHref          string
+	// NextPageSelector points at the "older posts" link of a listing page.
+	NextPageSelector string
+	// PageURLTemplate builds listing page URLs, with {page} replaced by the
+	// page number (the first page being BlogHref itself).
+	PageURLTemplate string
+	Rules           ValidationRules
+	// HrefRule and NameRule, when set, replace the plain selectors above.
+	HrefRule *ExtractionRule
+	NameRule *ExtractionRule
+	// Platform, when set, reads the latest article through the blog engine's
+	// own API or feed instead of the selectors.
+	Platform Platform
+	// FeedHref is the RSS or Atom feed that feed-based platforms read first. A
+	// blog with a feed and no platform is read from its feed.
+	FeedHref string
+	// Script is a Starlark extraction script taking precedence over both the
+	// platform and the selectors, for sites they cannot handle.
+	Script string
+	// Shadow is an alternative strategy run next to the live one, whose
+	// results are compared but never published.
+	Shadow *ExtractionStrategy
+	// Archived blogs stay on the site but are no longer scraped.
+	Archived bool
+	// Disabled blogs are neither scraped nor shown, until enabled again. It
+	// is set by hand and never synced from the directory file.
+	Disabled bool
+	// Tags are the names of the tags of the blog, sorted.
+	Tags []string
+}
+
+// ExtractionStrategy holds the settings deciding how a blog's latest article
+// is read.
+type ExtractionStrategy struct {
+	ArticleHrefSelector string          `json:"articleHrefSelector,omitempty"`
+	ArticleNameSelector string          `json:"articleNameSelector,omitempty"`
+	HrefRule            *ExtractionRule `json:"hrefRule,omitempty"`
+	NameRule            *ExtractionRule `json:"nameRule,omitempty"`
+	Platform            Platform        `json:"platform,omitempty"`
+	FeedHref            string          `json:"feed,omitempty"`
+	Script              string          `json:"script,omitempty"`
+}
+
+// WithStrategy returns a copy of the configuration reading articles with the
+// given strategy instead of its own.
+func (c BlogConfig) WithStrategy(strategy ExtractionStrategy) BlogConfig {
+	c.ArticleHrefSelector = strategy.ArticleHrefSelector
+	c.ArticleNameSelector = strategy.ArticleNameSelector
+	c.HrefRule = strategy.HrefRule
+	c.NameRule = strategy.NameRule
+	c.Platform = strategy.Platform
+	c.FeedHref = strategy.FeedHref
+	c.Script = strategy.Script
+	c.Shadow = nil
+	return c
+}
+
+// Platform is the blog engine a blog runs on.
+type Platform string
+
+const (
+	// PlatformAuto detects the engine from the blog's markup.
+	PlatformAuto Platform = "auto"
+	// PlatformFeed reads the RSS or Atom feed the blog advertises.
+	PlatformFeed      Platform = "feed"
+	PlatformWordPress Platform = "wordpress"
+	PlatformGhost     Platform = "ghost"
+	PlatformSubstack  Platform = "substack"
+	PlatformMedium    Platform = "medium"
+	PlatformHugo      Platform = "hugo"
+	PlatformJekyll    Platform = "jekyll"
+	PlatformHashnode  Platform = "hashnode"
+)
+
+// Platforms lists every platform value a blog configuration accepts.
+var Platforms = []Platform{
+	PlatformAuto,
+	PlatformFeed,
+	PlatformWordPress,
+	PlatformGhost,
+	PlatformSubstack,
+	PlatformMedium,
+	PlatformHugo,
+	PlatformJekyll,
+	PlatformHashnode,
+}
+
+const (
+	EngineCSS   = "css"
+	EngineXPath = "xpath"
+)
+
+// ExtractionRule describes how to read one field of the latest article.
+type ExtractionRule struct {
+	// Engine is EngineCSS (the default) or EngineXPath.
+	Engine   string `json:"engine,omitempty"`
+	Selector string `json:"selector"`
+	// Attribute is the attribute to read, e.g. "href", "data-url", "title",
+	// "content" or "onclick". "text" reads the element text.
+	Attribute string `json:"attribute,omitempty"`
+	// Regex is matched against the value read; its first capture group, or
+	// the whole match when it has none, becomes the value.
+	Regex string `json:"regex,omitempty"`
+	// Index picks the match to use, starting at 0.
+	Index int `json:"index,omitempty"`
+}
+
+// ValidationRules clean up and check what the selectors extracted. A scrape
+// breaking one of them is treated as failed.
+type ValidationRules struct {
+	// TitleReplacements are applied in order to the article name.
+	TitleReplacements []TitleReplacement `json:"titleReplacements,omitempty"`
+	// HrefPattern is a regular expression the article href must match.
+	HrefPattern string `json:"hrefPattern,omitempty"`
+	// TitleBlocklist lists generic titles (case-insensitive) that are never
+	// valid article names, e.g. "Blog" or "Read more".
+	TitleBlocklist []string `json:"titleBlocklist,omitempty"`
+}
+
+type TitleReplacement struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+type ScrapeStatus struct {
+	BlogName            string
+	LastAttemptAt       time.Time
+	LastSuccessAt       *time.Time
+	LastError           string
+	ConsecutiveFailures int
+	// LayoutFingerprint describes the page structure around the matched
+	// article element at the latest successful scrape.
+	LayoutFingerprint string
+	// LayoutSimilarity compares the two latest fingerprints, from 0 to 1.
+	LayoutSimilarity *float64
+	// LayoutChangedAt is when a significant layout change was last detected.
+	LayoutChangedAt *time.Time
+	// FinalURL is where the latest fetch of the blog page ended up after
+	// following RedirectChain.
+	FinalURL      string
+	RedirectChain []Redirect
+	// PermanentRedirect is set when every redirect of the chain was permanent.
+	PermanentRedirect bool
+}
+
+// Redirect is one hop followed while fetching a page.
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+}
+
+// Statuses of a BlogHrefChange.
+const (
+	HrefChangePending  = "pending"
+	HrefChangeApplied  = "applied"
+	HrefChangeRejected = "rejected"
+)
+
+// BlogHrefChange is a move of a blog to a new URL, detected from a permanent
+// redirect. Moves that are safe to follow are applied right away; the others
+// wait for review.
+type BlogHrefChange struct {
+	ID         int64
+	BlogName   string
+	OldHref    string
+	NewHref    string
+	DetectedAt time.Time
+	Status     string
+	ResolvedAt *time.Time
+}
+
+type Article struct {
+	Name string
+	Href string
+}
+
+// PageSnapshot is a copy of a listing page as fetched by the scraper.
+type PageSnapshot struct {
+	ID        int64
+	BlogName  string
+	PageURL   string
+	FetchedAt time.Time
+	// Content is the raw page, stored compressed.
+	Content []byte
+	// MatchedElement is the HTML of the element the href selector matched,
+	// empty when nothing matched.
+	MatchedElement string
+}
+
+// ShadowResult compares the live and shadow extractions of one scrape.
+type ShadowResult struct {
+	BlogName    string
+	CheckedAt   time.Time
+	LiveName    string
+	LiveHref    string
+	LiveError   string
+	ShadowName  string
+	ShadowHref  string
+	ShadowError string
+	// Agreed is set when both extractions succeeded with the same article.
+	Agreed bool
+}
+
+// ShadowStats summarizes the shadow results of a blog over a period.
+type ShadowStats struct {
+	BlogName      string    `json:"blogName"`
+	Runs          int       `json:"runs"`
+	Agreements    int       `json:"agreements"`
+	AgreementRate float64   `json:"agreementRate"`
+	FirstRunAt    time.Time `json:"firstRunAt"`
+	LastRunAt     time.Time `json:"lastRunAt"`
+	// LastDisagreementAt is unset when every run of the period agreed.
+	LastDisagreementAt *time.Time `json:"lastDisagreementAt"`
+	// LastDisagreement details the most recent disagreement.
+	LastDisagreement *ShadowDisagreement `json:"lastDisagreement,omitempty"`
+}
+
+type ShadowDisagreement struct {
+	LiveName    string `json:"liveName"`
+	LiveHref    string `json:"liveHref"`
+	LiveError   string `json:"liveError,omitempty"`
+	ShadowName  string `json:"shadowName"`
+	ShadowHref  string `json:"shadowHref"`
+	ShadowError string `json:"shadowError,omitempty"`
+}
+
+// LinkKind names a link shown for a blog.
+type LinkKind string
+
+const (
+	LinkBlog    LinkKind = "blog"
+	LinkArticle LinkKind = "article"
+	LinkGitHub  LinkKind = "github"
+)
+
+// LinkStatus is the outcome of a link check.
+type LinkStatus string
+
+const (
+	LinkOK LinkStatus = "ok"
+	// LinkNotFound is a 404 or 410 response.
+	LinkNotFound LinkStatus = "not_found"
+	// LinkParked is a domain showing a parking or for-sale page.
+	LinkParked LinkStatus = "parked"
+	// LinkSoft404 is a missing page answered with a success status, usually
+	// by redirecting to the home page.
+	LinkSoft404    LinkStatus = "soft_404"
+	LinkTLSExpired LinkStatus = "tls_expired"
+	LinkTLSInvalid LinkStatus = "tls_invalid"
+	// LinkError is any other failure, such as a timeout or a server error,
+	// which may be temporary.
+	LinkError LinkStatus = "error"
+)
+
+// Broken reports whether the status shows the link is dead rather than
+// temporarily failing.
+func (s LinkStatus) Broken() bool {
+	return s != LinkOK && s != LinkError
+}
+
+// LinkCheck is the outcome of the latest check of one link of a blog.
+type LinkCheck struct {
+	BlogName   string     `json:"blogName"`
+	Kind       LinkKind   `json:"kind"`
+	URL        string     `json:"url"`
+	CheckedAt  time.Time  `json:"checkedAt"`
+	StatusCode int        `json:"statusCode,omitempty"`
+	Status     LinkStatus `json:"status"`
+	Detail     string     `json:"detail,omitempty"`
+}
+
+// BlogIcon is a blog's favicon or GitHub avatar, normalized into a PNG.
+type BlogIcon struct {
+	BlogName  string
+	SourceURL string
+	Content   []byte
+	// ContentHash is the hex-encoded SHA-256 of Content.
+	ContentHash string
+	FetchedAt   time.Time
+}
+
+// iconVersionLength is how much of the content hash versions icon URLs.
+const iconVersionLength = 16
+
+// IconVersion returns the version icon URLs carry for an icon content hash.
+func IconVersion(contentHash string) string {
+	if len(contentHash) > iconVersionLength {
+		return contentHash[:iconVersionLength]
+	}
+	return contentHash
+}
+
+// IconPath returns the API path serving a blog icon, versioned so that it
+// can be cached for long.
+func IconPath(blogName string, contentHash string) string {
+	return "/api/blogs/" + url.PathEscape(blogName) + "/icon?v=" + IconVersion(contentHash)
+}
+
+// GitHubProfile is the public GitHub profile of an individual blogger.
+type GitHubProfile struct {
+	Login     string `json:"login"`
+	Name      string `json:"name,omitempty"`
+	Bio       string `json:"bio,omitempty"`
+	Followers int    `json:"followers"`
+	// TopRepos are the blogger's most starred repositories, forks excluded.
+	TopRepos  []GitHubRepo `json:"topRepos"`
+	FetchedAt time.Time    `json:"fetchedAt"`
+}
+
+// GitHubRepo is a repository listed on a GitHub profile.
+type GitHubRepo struct {
+	Name        string `json:"name"`
+	Href        string `json:"href"`
+	Description string `json:"description,omitempty"`
+	Stars       int    `json:"stars"`
+}
+
+// Tag is a topic blogs are tagged with, e.g. "databases".
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Blogs is the number of blogs listed with the tag by default.
+	Blogs int `json:"blogs"`
 }
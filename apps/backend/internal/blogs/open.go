@@ -0,0 +1,95 @@
+package blogs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/nesco/techblogs/backend/internal/database"
+	"github.com/nesco/techblogs/backend/migrations"
+)
+
+// defaultDBPath is the SQLite database used when DB_PATH is not set.
+const defaultDBPath = "./data/techblogs.db"
+
+// Database is an open database along with its repository and the
+// migrations of its schema.
+type Database struct {
+	DB *sql.DB
+	// Repo is the repository of the database, with the query timeout of
+	// QUERY_TIMEOUT.
+	Repo     *Repository
+	Migrator *database.Migrator
+	// Driver is "postgres" or "sqlite"; Path and SQLiteOptions are only set
+	// for SQLite.
+	Driver        string
+	Path          string
+	SQLiteOptions database.SQLiteOptions
+}
+
+// OpenFromEnv opens the database the commands share: PostgreSQL at
+// DATABASE_URL when it is set, and the SQLite file at DB_PATH otherwise, with
+// the options of database.SQLiteOptionsFromEnv. readOnly only applies to
+// SQLite. The schema version is not checked, so that it can be migrated
+// first: see CheckVersion.
+func OpenFromEnv(readOnly bool) (*Database, error) {
+	queryTimeout := DefaultQueryTimeout
+	if value := os.Getenv("QUERY_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid QUERY_TIMEOUT %q: expected a duration such as 5s", value)
+		}
+		queryTimeout = timeout
+	}
+
+	d := &Database{}
+	var schema fs.FS
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		db, err := database.InitPostgres(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize database: %w", err)
+		}
+		d.DB, d.Repo, d.Driver, schema = db, NewPostgresRepository(db), "postgres", migrations.Postgres
+	} else {
+		d.Path = os.Getenv("DB_PATH")
+		if d.Path == "" {
+			d.Path = defaultDBPath
+		}
+
+		options, err := database.SQLiteOptionsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		options.ReadOnly = readOnly
+		db, err := database.OpenSQLite(d.Path, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize database: %w", err)
+		}
+		d.DB, d.Repo, d.Driver, d.SQLiteOptions, schema = db, NewRepository(db), "sqlite", options, migrations.SQLite
+	}
+
+	migrator, err := database.NewMigrator(d.DB, schema)
+	if err != nil {
+		d.DB.Close()
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	d.Migrator = migrator
+	d.Repo = d.Repo.WithQueryTimeout(queryTimeout)
+	return d, nil
+}
+
+// CheckVersion fails when the schema is older than the migrations.
+func (d *Database) CheckVersion(ctx context.Context) error {
+	if err := d.Migrator.CheckVersion(ctx); err != nil {
+		return fmt.Errorf("database schema is out of date, run techblogs-api migrate up: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (d *Database) Close() error {
+	return d.DB.Close()
+}
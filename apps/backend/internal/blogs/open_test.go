@@ -0,0 +1,46 @@
+package blogs
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenFromEnv(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "techblogs.db"))
+	t.Setenv("QUERY_TIMEOUT", "2s")
+
+	opened, err := OpenFromEnv(false)
+	if err != nil {
+		t.Fatalf("OpenFromEnv() error = %v", err)
+	}
+	defer opened.Close()
+
+	if opened.Driver != "sqlite" || opened.SQLiteOptions.JournalMode != "WAL" || opened.Repo.queryTimeout != 2*time.Second {
+		t.Errorf("opened = %s %+v, query timeout %v", opened.Driver, opened.SQLiteOptions, opened.Repo.queryTimeout)
+	}
+
+	ctx := context.Background()
+	if err := opened.CheckVersion(ctx); err == nil || !strings.Contains(err.Error(), "migrate up") {
+		t.Errorf("CheckVersion() error = %v, want the schema out of date", err)
+	}
+	if _, err := opened.Migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if err := opened.CheckVersion(ctx); err != nil {
+		t.Errorf("CheckVersion() error = %v after migrating", err)
+	}
+}
+
+func TestOpenFromEnv_InvalidQueryTimeout(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "techblogs.db"))
+	t.Setenv("QUERY_TIMEOUT", "soon")
+
+	if _, err := OpenFromEnv(false); err == nil || !strings.Contains(err.Error(), "QUERY_TIMEOUT") {
+		t.Errorf("OpenFromEnv() error = %v, want an invalid QUERY_TIMEOUT", err)
+	}
+}
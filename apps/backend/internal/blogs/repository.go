@@ -1,26 +1,133 @@
 package blogs
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 )
 
+// DefaultQueryTimeout bounds how long a repository call may wait on the
+// database, so that a locked database file cannot pin a request forever.
+const DefaultQueryTimeout = 5 * time.Second
+
+// Repository implements Store with SQL kept portable between SQLite and
+// PostgreSQL; only placeholders differ, and are rewritten for PostgreSQL,
+// apart from full-text search, which each database does its own way.
 type Repository struct {
-	db *sql.DB
+	db           *sqlDB
+	queryTimeout time.Duration
+	postgres     bool
 }
 
+// NewRepository returns a repository on a SQLite database.
 func NewRepository(db *sql.DB) *Repository {
-	return &Repository{db: db}
+	return &Repository{db: &sqlDB{DB: db}, queryTimeout: DefaultQueryTimeout}
+}
+
+// NewPostgresRepository returns a repository on a PostgreSQL database
+// opened with the pgx driver.
+func NewPostgresRepository(db *sql.DB) *Repository {
+	return &Repository{db: &sqlDB{DB: db, numbered: true}, queryTimeout: DefaultQueryTimeout, postgres: true}
+}
+
+// WithQueryTimeout returns a repository whose calls time out after the given
+// duration instead of DefaultQueryTimeout. A zero duration disables the
+// timeout, leaving calls bounded by their context only.
+func (r *Repository) WithQueryTimeout(timeout time.Duration) *Repository {
+	return &Repository{db: r.db, queryTimeout: timeout, postgres: r.postgres}
+}
+
+// withTimeout derives the context a repository call runs its queries with.
+func (r *Repository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.queryTimeout)
+}
+
+// blogInfoColumns selects a cached blog along with the kinds of its links
+// whose latest check found them broken, for the URL currently shown, and
+// what its lifecycle state is computed from.
+const blogInfoColumns = `
+	blog_name, blog_href, latest_article_name, latest_article_href, kind, github_href,
+	last_new_article_at, description, site_name, lang, favicon_href, feed_href,
+	COALESCE((
+		SELECT archived
+		FROM blog_configs
+		WHERE blog_configs.blog_name = blog_cache.blog_name
+	), FALSE) AS archived,
+	(
+		SELECT content_hash
+		FROM blog_icons
+		WHERE blog_icons.blog_name = blog_cache.blog_name
+	) AS icon_hash,
+	(
+		SELECT string_agg(link_kind, ',')
+		FROM link_checks
+		WHERE link_checks.blog_name = blog_cache.blog_name
+			AND link_checks.status NOT IN ('ok', 'error')
+			AND link_checks.url = CASE link_checks.link_kind
+				WHEN 'blog' THEN blog_cache.blog_href
+				WHEN 'article' THEN blog_cache.latest_article_href
+				WHEN 'github' THEN blog_cache.github_href
+			END
+	) AS broken_links
+`
+
+// notDisabled filters out the cached blogs whose configuration is disabled.
+const notDisabled = `NOT EXISTS (
+	SELECT 1
+	FROM blog_configs
+	WHERE blog_configs.blog_name = blog_cache.blog_name AND blog_configs.disabled
+)`
+
+func scanBlogInfo(row rowScanner) (BlogInfo, error) {
+	var blog BlogInfo
+	var kind string
+	var lastNewArticle sql.NullTime
+	var archived bool
+	var iconHash, brokenLinks sql.NullString
+	err := row.Scan(
+		&blog.BlogName, &blog.BlogHref, &blog.LatestArticleName, &blog.LatestArticleHref, &kind, &blog.GitHubHref,
+		&lastNewArticle, &blog.Description, &blog.SiteName, &blog.Lang, &blog.FaviconHref, &blog.FeedHref,
+		&archived, &iconHash, &brokenLinks,
+	)
+	if err != nil {
+		return blog, err
+	}
+	blog.Kind = Kind(kind)
+	if lastNewArticle.Valid {
+		blog.LastNewArticleAt = &lastNewArticle.Time
+	}
+	blog.Lifecycle = LifecycleOf(blog.LastNewArticleAt, archived, time.Now())
+	if iconHash.Valid {
+		blog.IconHref = IconPath(blog.BlogName, iconHash.String)
+	}
+	if brokenLinks.String != "" {
+		for _, link := range strings.Split(brokenLinks.String, ",") {
+			blog.BrokenLinks = append(blog.BrokenLinks, LinkKind(link))
+		}
+	}
+	return blog, nil
 }
 
-func (r *Repository) GetAllBlogs() ([]BlogInfo, error) {
+func (r *Repository) GetAllBlogs(ctx context.Context) ([]BlogInfo, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT blog_name, blog_href, latest_article_name, latest_article_href, kind, github_href
+		SELECT ` + blogInfoColumns + `
 		FROM blog_cache
+		WHERE ` + notDisabled + `
 		ORDER BY DATE(updated_at) DESC, blog_name ASC
 	`
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query blogs: %w", err)
 	}
@@ -28,12 +135,10 @@ func (r *Repository) GetAllBlogs() ([]BlogInfo, error) {
 
 	var blogs []BlogInfo
 	for rows.Next() {
-		var blog BlogInfo
-		var kind string
-		if err := rows.Scan(&blog.BlogName, &blog.BlogHref, &blog.LatestArticleName, &blog.LatestArticleHref, &kind, &blog.GitHubHref); err != nil {
+		blog, err := scanBlogInfo(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan blog row: %w", err)
 		}
-		blog.Kind = Kind(kind)
 		blogs = append(blogs, blog)
 	}
 
@@ -41,17 +146,27 @@ func (r *Repository) GetAllBlogs() ([]BlogInfo, error) {
 		return nil, fmt.Errorf("error iterating blog rows: %w", err)
 	}
 
+	if err := r.attachGitHubProfiles(ctx, blogs); err != nil {
+		return nil, err
+	}
+	if err := r.attachTags(ctx, blogs); err != nil {
+		return nil, err
+	}
+
 	return blogs, nil
 }
 
-func (r *Repository) GetBlogsByKind(kind Kind) ([]BlogInfo, error) {
+func (r *Repository) GetBlogsByKind(ctx context.Context, kind Kind) ([]BlogInfo, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT blog_name, blog_href, latest_article_name, latest_article_href, kind, github_href
+		SELECT ` + blogInfoColumns + `
 		FROM blog_cache
-		WHERE kind = ?
+		WHERE kind = ? AND ` + notDisabled + `
 		ORDER BY DATE(updated_at) DESC, blog_name ASC
 	`
-	rows, err := r.db.Query(query, string(kind))
+	rows, err := r.db.QueryContext(ctx, query, string(kind))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query blogs by kind: %w", err)
 	}
@@ -59,12 +174,10 @@ func (r *Repository) GetBlogsByKind(kind Kind) ([]BlogInfo, error) {
 
 	var blogs []BlogInfo
 	for rows.Next() {
-		var blog BlogInfo
-		var kindStr string
-		if err := rows.Scan(&blog.BlogName, &blog.BlogHref, &blog.LatestArticleName, &blog.LatestArticleHref, &kindStr, &blog.GitHubHref); err != nil {
+		blog, err := scanBlogInfo(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan blog row: %w", err)
 		}
-		blog.Kind = Kind(kindStr)
 		blogs = append(blogs, blog)
 	}
 
@@ -72,16 +185,79 @@ func (r *Repository) GetBlogsByKind(kind Kind) ([]BlogInfo, error) {
 		return nil, fmt.Errorf("error iterating blog rows: %w", err)
 	}
 
+	if err := r.attachGitHubProfiles(ctx, blogs); err != nil {
+		return nil, err
+	}
+	if err := r.attachTags(ctx, blogs); err != nil {
+		return nil, err
+	}
+
 	return blogs, nil
 }
 
-func (r *Repository) GetAllBlogConfigs() ([]BlogConfig, error) {
-	query := `
-		SELECT blog_name, blog_href, kind, article_href_selector, article_name_selector, github_href
+const blogConfigColumns = `
+	blog_name, blog_href, kind, article_href_selector, article_name_selector, github_href,
+	next_page_selector, page_url_template, validation_rules, article_href_rule, article_name_rule,
+	platform, extraction_script, shadow_config, archived, feed_href, disabled
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBlogConfig(row rowScanner) (BlogConfig, error) {
+	var config BlogConfig
+	var kind, rules, hrefRule, nameRule, platform, shadow string
+	err := row.Scan(
+		&config.BlogName, &config.BlogHref, &kind, &config.ArticleHrefSelector, &config.ArticleNameSelector, &config.GitHubHref,
+		&config.NextPageSelector, &config.PageURLTemplate, &rules, &hrefRule, &nameRule,
+		&platform, &config.Script, &shadow, &config.Archived, &config.FeedHref, &config.Disabled,
+	)
+	if err != nil {
+		return config, err
+	}
+	config.Kind = Kind(kind)
+	config.Platform = Platform(platform)
+	if rules != "" {
+		if err := json.Unmarshal([]byte(rules), &config.Rules); err != nil {
+			return config, fmt.Errorf("invalid validation rules for %s: %w", config.BlogName, err)
+		}
+	}
+	if config.HrefRule, err = parseExtractionRule(hrefRule); err != nil {
+		return config, fmt.Errorf("invalid href rule for %s: %w", config.BlogName, err)
+	}
+	if config.NameRule, err = parseExtractionRule(nameRule); err != nil {
+		return config, fmt.Errorf("invalid name rule for %s: %w", config.BlogName, err)
+	}
+	if shadow != "" {
+		config.Shadow = &ExtractionStrategy{}
+		if err := json.Unmarshal([]byte(shadow), config.Shadow); err != nil {
+			return config, fmt.Errorf("invalid shadow config for %s: %w", config.BlogName, err)
+		}
+	}
+	return config, nil
+}
+
+func parseExtractionRule(raw string) (*ExtractionRule, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var rule ExtractionRule
+	if err := json.Unmarshal([]byte(raw), &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *Repository) GetAllBlogConfigs(ctx context.Context) ([]BlogConfig, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + blogConfigColumns + `
 		FROM blog_configs
 		ORDER BY blog_name
 	`
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query blog configs: %w", err)
 	}
@@ -89,12 +265,10 @@ func (r *Repository) GetAllBlogConfigs() ([]BlogConfig, error) {
 
 	var configs []BlogConfig
 	for rows.Next() {
-		var config BlogConfig
-		var kind string
-		if err := rows.Scan(&config.BlogName, &config.BlogHref, &kind, &config.ArticleHrefSelector, &config.ArticleNameSelector, &config.GitHubHref); err != nil {
+		config, err := scanBlogConfig(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan blog config row: %w", err)
 		}
-		config.Kind = Kind(kind)
 		configs = append(configs, config)
 	}
 
@@ -102,31 +276,466 @@ func (r *Repository) GetAllBlogConfigs() ([]BlogConfig, error) {
 		return nil, fmt.Errorf("error iterating blog config rows: %w", err)
 	}
 
+	tags, err := r.getBlogTags(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range configs {
+		configs[i].Tags = tags[configs[i].BlogName]
+	}
+
 	return configs, nil
 }
 
-func (r *Repository) GetBlogCache(blogName string) (*BlogInfo, error) {
+// SyncBlogConfigs inserts or updates the given blog configurations and
+// deletes the named ones, in a single transaction. The blog URL, kind and
+// GitHub link copied into the cache follow their configuration, and the tags
+// they name are created as needed. Updates keep whether a blog is disabled.
+func (r *Repository) SyncBlogConfigs(ctx context.Context, upserts []BlogConfig, deletes []string) error {
+	return r.SyncDirectory(ctx, upserts, deletes, nil, nil)
+}
+
+// SyncDirectory applies the changes of the directory file in a single
+// transaction: the blog configurations as SyncBlogConfigs does, then the tags
+// to insert or update and the ones to delete, untagging their blogs.
+func (r *Repository) SyncDirectory(ctx context.Context, upserts []BlogConfig, deletes []string, tagUpserts []Tag, tagDeletes []string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Blogs come first: they create the tags they use, and stop using the
+	// deleted ones
+	if err := syncBlogConfigs(ctx, tx, upserts, deletes); err != nil {
+		return err
+	}
+	if err := syncTags(ctx, tx, tagUpserts, tagDeletes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit directory: %w", err)
+	}
+	return nil
+}
+
+// syncBlogConfigs is SyncBlogConfigs within a transaction.
+func syncBlogConfigs(ctx context.Context, tx *sqlTx, upserts []BlogConfig, deletes []string) error {
+	for _, blogName := range deletes {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM blog_configs WHERE blog_name = ?`, blogName); err != nil {
+			return fmt.Errorf("failed to delete blog config %s: %w", blogName, err)
+		}
+	}
+
+	upsert, err := tx.PrepareContext(ctx, `
+		INSERT INTO blog_configs (`+blogConfigColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(blog_name) DO UPDATE SET
+			blog_href = excluded.blog_href,
+			kind = excluded.kind,
+			article_href_selector = excluded.article_href_selector,
+			article_name_selector = excluded.article_name_selector,
+			github_href = excluded.github_href,
+			next_page_selector = excluded.next_page_selector,
+			page_url_template = excluded.page_url_template,
+			validation_rules = excluded.validation_rules,
+			article_href_rule = excluded.article_href_rule,
+			article_name_rule = excluded.article_name_rule,
+			platform = excluded.platform,
+			extraction_script = excluded.extraction_script,
+			shadow_config = excluded.shadow_config,
+			archived = excluded.archived,
+			feed_href = excluded.feed_href
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare blog config upsert: %w", err)
+	}
+	defer upsert.Close()
+
+	for _, config := range upserts {
+		values, err := blogConfigValues(config)
+		if err != nil {
+			return err
+		}
+		if _, err := upsert.ExecContext(ctx, values...); err != nil {
+			return fmt.Errorf("failed to upsert blog config %s: %w", config.BlogName, err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE blog_cache SET blog_href = ?, kind = ?, github_href = ?
+			WHERE blog_name = ?
+		`, config.BlogHref, config.Kind, config.GitHubHref, config.BlogName)
+		if err != nil {
+			return fmt.Errorf("failed to update cached blog %s: %w", config.BlogName, err)
+		}
+		if err := setBlogTags(ctx, tx, config.BlogName, config.Tags); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// setBlogTags replaces the tags of a blog, creating the missing ones.
+func setBlogTags(ctx context.Context, tx *sqlTx, blogName string, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM blog_tags WHERE blog_name = ?`, blogName); err != nil {
+		return fmt.Errorf("failed to clear tags of %s: %w", blogName, err)
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO tags (name) VALUES (?) ON CONFLICT(name) DO NOTHING`, tag); err != nil {
+			return fmt.Errorf("failed to create tag %s: %w", tag, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO blog_tags (blog_name, tag) VALUES (?, ?)`, blogName, tag); err != nil {
+			return fmt.Errorf("failed to tag %s with %s: %w", blogName, tag, err)
+		}
+	}
+	return nil
+}
+
+// getBlogTags returns the sorted tag names of each tagged blog, or of the
+// named blog only when blogName is set.
+func (r *Repository) getBlogTags(ctx context.Context, blogName string) (map[string][]string, error) {
+	query := `SELECT blog_name, tag FROM blog_tags`
+	var args []any
+	if blogName != "" {
+		query += ` WHERE blog_name = ?`
+		args = append(args, blogName)
+	}
+	query += ` ORDER BY blog_name, tag`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blog tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var name, tag string
+		if err := rows.Scan(&name, &tag); err != nil {
+			return nil, fmt.Errorf("failed to scan blog tag row: %w", err)
+		}
+		tags[name] = append(tags[name], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blog tag rows: %w", err)
+	}
+	return tags, nil
+}
+
+// GetTags returns every tag, sorted by name, with the number of blogs listed
+// with it by default: cached, not disabled, and in one of ShownLifecycles.
+func (r *Repository) GetTags(ctx context.Context) ([]Tag, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT name, description FROM tags ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan tag row: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag rows: %w", err)
+	}
+
+	// Lifecycle states are computed, so shown blogs are counted here
+	shown, err := r.GetAllBlogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, blog := range FilterByLifecycle(shown, ShownLifecycles...) {
+		for _, tag := range blog.Tags {
+			counts[tag]++
+		}
+	}
+	for i := range tags {
+		tags[i].Blogs = counts[tags[i].Name]
+	}
+	return tags, nil
+}
+
+// syncTags inserts or updates the given tags and deletes the named ones,
+// untagging their blogs.
+func syncTags(ctx context.Context, tx *sqlTx, upserts []Tag, deletes []string) error {
+	for _, name := range deletes {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM tags WHERE name = ?`, name); err != nil {
+			return fmt.Errorf("failed to delete tag %s: %w", name, err)
+		}
+	}
+	for _, tag := range upserts {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO tags (name, description) VALUES (?, ?)
+			ON CONFLICT(name) DO UPDATE SET description = excluded.description
+		`, tag.Name, tag.Description)
+		if err != nil {
+			return fmt.Errorf("failed to save tag %s: %w", tag.Name, err)
+		}
+	}
+	return nil
+}
+
+// Search returns the shown blogs and the articles containing every word of
+// query, the last one as the prefix of a word, the best matches first: blogs
+// by name and description, articles by title. Titles are highlighted whole
+// and descriptions as a snippet. A query without any word matches nothing.
+func (r *Repository) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var rows *sql.Rows
+	var err error
+	if r.postgres {
+		marks := fmt.Sprintf("StartSel=%s, StopSel=%s", searchMarkStart, searchMarkEnd)
+		titleOptions := marks + ", HighlightAll=true"
+		snippetOptions := marks + ", MinWords=8, MaxWords=16"
+		rows, err = r.db.QueryContext(ctx, postgresSearchQuery, titleOptions, snippetOptions, tsQuery(terms), titleOptions, tsQuery(terms), limit)
+	} else {
+		rows, err = r.db.QueryContext(ctx, `
+			SELECT search_index.kind, search_index.blog_name, search_index.href,
+				highlight(search_index, 0, ?, ?),
+				snippet(search_index, 1, ?, ?, '…', 16)
+			FROM search_index
+			JOIN blog_cache ON blog_cache.blog_name = search_index.blog_name
+			WHERE search_index MATCH ? AND `+notDisabled+`
+			ORDER BY bm25(search_index, 10.0, 1.0)
+			LIMIT ?
+		`, searchMarkStart, searchMarkEnd, searchMarkStart, searchMarkEnd, ftsQuery(terms), limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var kind SearchKind
+		var blogName, href, title, snippet string
+		if err := rows.Scan(&kind, &blogName, &href, &title, &snippet); err != nil {
+			return nil, fmt.Errorf("failed to scan search result row: %w", err)
+		}
+		results = append(results, newSearchResult(kind, blogName, href, title, snippet))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search result rows: %w", err)
+	}
+	return results, nil
+}
+
+// postgresBlogDocument is the weighted document of a cached blog, as indexed
+// by idx_blog_cache_search.
+const postgresBlogDocument = `(setweight(to_tsvector('simple', blog_cache.blog_name), 'A') || setweight(to_tsvector('simple', blog_cache.description), 'D'))`
+
+// postgresSearchQuery is Search on PostgreSQL, taking the ts_headline options
+// of each highlighted column (whole titles, description snippets) and the
+// tsquery of each half.
+const postgresSearchQuery = `
+	SELECT kind, blog_name, href, title, snippet
+	FROM (
+		SELECT 'blog' AS kind, blog_cache.blog_name, blog_cache.blog_href AS href,
+			ts_headline('simple', blog_cache.blog_name, query, ?) AS title,
+			CASE WHEN blog_cache.description = '' THEN '' ELSE ts_headline('simple', blog_cache.description, query, ?) END AS snippet,
+			ts_rank(` + postgresBlogDocument + `, query) AS rank
+		FROM blog_cache
+		CROSS JOIN to_tsquery('simple', ?) AS query
+		WHERE ` + postgresBlogDocument + ` @@ query AND ` + notDisabled + `
+		UNION ALL
+		SELECT 'article', articles.blog_name, articles.article_href,
+			ts_headline('simple', articles.article_name, query, ?), '',
+			ts_rank(setweight(to_tsvector('simple', articles.article_name), 'A'), query)
+		FROM articles
+		JOIN blog_cache ON blog_cache.blog_name = articles.blog_name
+		CROSS JOIN to_tsquery('simple', ?) AS query
+		WHERE to_tsvector('simple', articles.article_name) @@ query AND ` + notDisabled + `
+	) AS results
+	ORDER BY rank DESC, title
+	LIMIT ?
+`
+
+// SuggestBlogNames returns the names of the shown blogs containing prefix,
+// case-insensitively, the ones starting with it first.
+func (r *Repository) SuggestBlogNames(ctx context.Context, prefix string, limit int) ([]string, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
 	query := `
-		SELECT blog_name, blog_href, latest_article_name, latest_article_href, kind, github_href
+		SELECT blog_name
 		FROM blog_cache
+		WHERE LOWER(blog_name) LIKE ? ESCAPE '\' AND ` + notDisabled + `
+		ORDER BY LOWER(blog_name) LIKE ? ESCAPE '\' DESC, LOWER(blog_name)
+		LIMIT ?
+	`
+	rows, err := r.db.QueryContext(ctx, query, "%"+escaped+"%", escaped+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blog names: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan blog name row: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blog name rows: %w", err)
+	}
+	return names, nil
+}
+
+// blogConfigValues returns the values of blogConfigColumns, the reverse of
+// scanBlogConfig.
+func blogConfigValues(config BlogConfig) ([]any, error) {
+	var rules, hrefRule, nameRule, shadow string
+	encoded, err := json.Marshal(config.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode validation rules for %s: %w", config.BlogName, err)
+	}
+	if string(encoded) != "{}" {
+		rules = string(encoded)
+	}
+	for _, field := range []struct {
+		rule *ExtractionRule
+		raw  *string
+	}{{config.HrefRule, &hrefRule}, {config.NameRule, &nameRule}} {
+		if field.rule == nil {
+			continue
+		}
+		encoded, err := json.Marshal(field.rule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode extraction rule for %s: %w", config.BlogName, err)
+		}
+		*field.raw = string(encoded)
+	}
+	if config.Shadow != nil {
+		encoded, err := json.Marshal(config.Shadow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode shadow config for %s: %w", config.BlogName, err)
+		}
+		shadow = string(encoded)
+	}
+
+	return []any{
+		config.BlogName, config.BlogHref, config.Kind, config.ArticleHrefSelector, config.ArticleNameSelector, config.GitHubHref,
+		config.NextPageSelector, config.PageURLTemplate, rules, hrefRule, nameRule,
+		config.Platform, config.Script, shadow, config.Archived, config.FeedHref, config.Disabled,
+	}, nil
+}
+
+func (r *Repository) GetBlogConfig(ctx context.Context, blogName string) (*BlogConfig, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + blogConfigColumns + `
+		FROM blog_configs
 		WHERE blog_name = ?
 	`
-	var blog BlogInfo
-	var kind string
-	err := r.db.QueryRow(query, blogName).Scan(&blog.BlogName, &blog.BlogHref, &blog.LatestArticleName, &blog.LatestArticleHref, &kind, &blog.GitHubHref)
+	config, err := scanBlogConfig(r.db.QueryRowContext(ctx, query, blogName))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blog config: %w", err)
+	}
+	tags, err := r.getBlogTags(ctx, blogName)
+	if err != nil {
+		return nil, err
+	}
+	config.Tags = tags[blogName]
+	return &config, nil
+}
+
+// SetBlogDisabled disables or enables a blog. It reports whether the blog
+// exists.
+func (r *Repository) SetBlogDisabled(ctx context.Context, blogName string, disabled bool) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE blog_configs SET disabled = ? WHERE blog_name = ?`, disabled, blogName)
+	if err != nil {
+		return false, fmt.Errorf("failed to update blog config: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update blog config: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ClearBlogCache removes the cached latest article of a blog, or of every
+// blog when blogName is empty, until the next scrape. It returns the number
+// of blogs removed.
+func (r *Repository) ClearBlogCache(ctx context.Context, blogName string) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var result sql.Result
+	var err error
+	if blogName == "" {
+		result, err = r.db.ExecContext(ctx, `DELETE FROM blog_cache`)
+	} else {
+		result, err = r.db.ExecContext(ctx, `DELETE FROM blog_cache WHERE blog_name = ?`, blogName)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear blog cache: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear blog cache: %w", err)
+	}
+	return n, nil
+}
+
+func (r *Repository) GetBlogCache(ctx context.Context, blogName string) (*BlogInfo, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + blogInfoColumns + `
+		FROM blog_cache
+		WHERE blog_name = ?
+	`
+	blog, err := scanBlogInfo(r.db.QueryRowContext(ctx, query, blogName))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get blog cache: %w", err)
 	}
-	blog.Kind = Kind(kind)
 	return &blog, nil
 }
 
-func (r *Repository) UpsertBlogCache(blog BlogInfo) error {
+func (r *Repository) UpsertBlogCache(ctx context.Context, blog BlogInfo) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	// Check if data has changed
-	existing, err := r.GetBlogCache(blog.BlogName)
+	existing, err := r.GetBlogCache(ctx, blog.BlogName)
 	if err != nil {
 		return err
 	}
@@ -155,9 +764,838 @@ func (r *Repository) UpsertBlogCache(blog BlogInfo) error {
 			github_href = excluded.github_href,
 			updated_at = excluded.updated_at
 	`
-	_, err = r.db.Exec(query, blog.BlogName, blog.BlogHref, blog.LatestArticleName, blog.LatestArticleHref, string(blog.Kind), blog.GitHubHref, now)
+	_, err = r.db.ExecContext(ctx, query, blog.BlogName, blog.BlogHref, blog.LatestArticleName, blog.LatestArticleHref, string(blog.Kind), blog.GitHubHref, now)
 	if err != nil {
 		return fmt.Errorf("failed to upsert blog cache: %w", err)
 	}
 	return nil
 }
+
+// UpdateBlogMetadata stores the metadata read from a blog page. It is not
+// an update of the blog for readers, so updated_at is left untouched.
+func (r *Repository) UpdateBlogMetadata(ctx context.Context, blogName string, metadata BlogMetadata) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE blog_cache SET
+			description = ?,
+			site_name = ?,
+			lang = ?,
+			favicon_href = ?,
+			feed_href = ?
+		WHERE blog_name = ?
+	`
+	_, err := r.db.ExecContext(ctx, query, metadata.Description, metadata.SiteName, metadata.Lang, metadata.FaviconHref, metadata.FeedHref, blogName)
+	if err != nil {
+		return fmt.Errorf("failed to update blog metadata: %w", err)
+	}
+	return nil
+}
+
+// RecordNewArticle marks a blog as having just published an article never
+// seen before, which keeps it active.
+func (r *Repository) RecordNewArticle(ctx context.Context, blogName string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE blog_cache SET last_new_article_at = ? WHERE blog_name = ?`
+	if _, err := r.db.ExecContext(ctx, query, time.Now(), blogName); err != nil {
+		return fmt.Errorf("failed to record new article: %w", err)
+	}
+	return nil
+}
+
+// InsertArticles records articles of a blog, skipping the ones already known.
+// It returns the number of articles that were new.
+func (r *Repository) InsertArticles(ctx context.Context, blogName string, articles []Article) (int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO articles (blog_name, article_name, article_href)
+		VALUES (?, ?, ?)
+		ON CONFLICT(blog_name, article_href) DO NOTHING
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare article insert: %w", err)
+	}
+	defer stmt.Close()
+
+	inserted := 0
+	for _, article := range articles {
+		res, err := stmt.ExecContext(ctx, blogName, article.Name, article.Href)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert article %s: %w", article.Href, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count inserted articles: %w", err)
+		}
+		inserted += int(n)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit articles: %w", err)
+	}
+	return inserted, nil
+}
+
+func (r *Repository) RecordScrapeSuccess(ctx context.Context, blogName string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO scrape_status (blog_name, last_attempt_at, last_success_at, last_error, consecutive_failures)
+		VALUES (?, ?, ?, '', 0)
+		ON CONFLICT(blog_name) DO UPDATE SET
+			last_attempt_at = excluded.last_attempt_at,
+			last_success_at = excluded.last_success_at,
+			last_error = '',
+			consecutive_failures = 0
+	`
+	now := time.Now()
+	if _, err := r.db.ExecContext(ctx, query, blogName, now, now); err != nil {
+		return fmt.Errorf("failed to record scrape success: %w", err)
+	}
+	return nil
+}
+
+// RecordScrapeFailure marks the latest scrape of a blog as failed. The blog
+// cache is left untouched so the last good article keeps being served.
+func (r *Repository) RecordScrapeFailure(ctx context.Context, blogName string, reason string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO scrape_status (blog_name, last_attempt_at, last_error, consecutive_failures)
+		VALUES (?, ?, ?, 1)
+		ON CONFLICT(blog_name) DO UPDATE SET
+			last_attempt_at = excluded.last_attempt_at,
+			last_error = excluded.last_error,
+			consecutive_failures = scrape_status.consecutive_failures + 1
+	`
+	if _, err := r.db.ExecContext(ctx, query, blogName, time.Now(), reason); err != nil {
+		return fmt.Errorf("failed to record scrape failure: %w", err)
+	}
+	return nil
+}
+
+const scrapeStatusColumns = `
+	blog_name, last_attempt_at, last_success_at, last_error, consecutive_failures,
+	layout_fingerprint, layout_similarity, layout_changed_at,
+	final_url, redirect_chain, permanent_redirect
+`
+
+func scanScrapeStatus(row rowScanner) (ScrapeStatus, error) {
+	var status ScrapeStatus
+	var lastSuccess, layoutChanged sql.NullTime
+	var similarity sql.NullFloat64
+	var chain string
+	err := row.Scan(
+		&status.BlogName, &status.LastAttemptAt, &lastSuccess, &status.LastError, &status.ConsecutiveFailures,
+		&status.LayoutFingerprint, &similarity, &layoutChanged,
+		&status.FinalURL, &chain, &status.PermanentRedirect,
+	)
+	if err != nil {
+		return status, err
+	}
+	if lastSuccess.Valid {
+		status.LastSuccessAt = &lastSuccess.Time
+	}
+	if similarity.Valid {
+		status.LayoutSimilarity = &similarity.Float64
+	}
+	if layoutChanged.Valid {
+		status.LayoutChangedAt = &layoutChanged.Time
+	}
+	if chain != "" {
+		if err := json.Unmarshal([]byte(chain), &status.RedirectChain); err != nil {
+			return status, fmt.Errorf("invalid redirect chain for %s: %w", status.BlogName, err)
+		}
+	}
+	return status, nil
+}
+
+func (r *Repository) GetScrapeStatus(ctx context.Context, blogName string) (*ScrapeStatus, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + scrapeStatusColumns + `
+		FROM scrape_status
+		WHERE blog_name = ?
+	`
+	status, err := scanScrapeStatus(r.db.QueryRowContext(ctx, query, blogName))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scrape status: %w", err)
+	}
+	return &status, nil
+}
+
+// RecordRedirects stores the redirects followed by the latest fetch of a
+// blog page and where it ended up.
+func (r *Repository) RecordRedirects(ctx context.Context, blogName string, finalURL string, chain []Redirect, permanent bool) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var encoded []byte
+	if len(chain) > 0 {
+		var err error
+		if encoded, err = json.Marshal(chain); err != nil {
+			return fmt.Errorf("failed to encode redirect chain: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO scrape_status (blog_name, last_attempt_at, final_url, redirect_chain, permanent_redirect)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(blog_name) DO UPDATE SET
+			final_url = excluded.final_url,
+			redirect_chain = excluded.redirect_chain,
+			permanent_redirect = excluded.permanent_redirect
+	`
+	if _, err := r.db.ExecContext(ctx, query, blogName, time.Now(), finalURL, string(encoded), permanent); err != nil {
+		return fmt.Errorf("failed to record redirects: %w", err)
+	}
+	return nil
+}
+
+// QueueBlogHrefChange adds a blog URL change to the review queue. Changes
+// already queued, applied or rejected are not queued again; it reports
+// whether the change is new.
+func (r *Repository) QueueBlogHrefChange(ctx context.Context, blogName string, oldHref string, newHref string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO blog_href_changes (blog_name, old_href, new_href, detected_at, status)
+		VALUES (?, ?, ?, ?, 'pending')
+		ON CONFLICT(blog_name, new_href) DO NOTHING
+	`
+	result, err := r.db.ExecContext(ctx, query, blogName, oldHref, newHref, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to queue blog href change: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to queue blog href change: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ApplyBlogHrefChange moves a blog to a new URL and records the change as
+// applied. The cached blog entry follows without being marked as updated.
+func (r *Repository) ApplyBlogHrefChange(ctx context.Context, blogName string, oldHref string, newHref string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if err := moveBlogHref(ctx, tx, blogName, newHref); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO blog_href_changes (blog_name, old_href, new_href, detected_at, status, resolved_at)
+		VALUES (?, ?, ?, ?, 'applied', ?)
+		ON CONFLICT(blog_name, new_href) DO UPDATE SET
+			status = 'applied',
+			resolved_at = excluded.resolved_at
+	`, blogName, oldHref, newHref, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to record blog href change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit blog href change: %w", err)
+	}
+	return nil
+}
+
+// ResolveBlogHrefChange approves or rejects a pending blog URL change,
+// moving the blog when it is approved. It returns nil, nil when there is no
+// pending change with that id.
+func (r *Repository) ResolveBlogHrefChange(ctx context.Context, id int64, approve bool) (*BlogHrefChange, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var change BlogHrefChange
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, blog_name, old_href, new_href, detected_at
+		FROM blog_href_changes
+		WHERE id = ? AND status = 'pending'
+	`, id).Scan(&change.ID, &change.BlogName, &change.OldHref, &change.NewHref, &change.DetectedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blog href change: %w", err)
+	}
+
+	change.Status = HrefChangeRejected
+	if approve {
+		change.Status = HrefChangeApplied
+		if err := moveBlogHref(ctx, tx, change.BlogName, change.NewHref); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	change.ResolvedAt = &now
+	if _, err := tx.ExecContext(ctx, `UPDATE blog_href_changes SET status = ?, resolved_at = ? WHERE id = ?`, change.Status, now, id); err != nil {
+		return nil, fmt.Errorf("failed to resolve blog href change: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit blog href change: %w", err)
+	}
+	return &change, nil
+}
+
+func moveBlogHref(ctx context.Context, tx *sqlTx, blogName string, newHref string) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE blog_configs SET blog_href = ? WHERE blog_name = ?`, newHref, blogName); err != nil {
+		return fmt.Errorf("failed to update blog href: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE blog_cache SET blog_href = ? WHERE blog_name = ?`, newHref, blogName); err != nil {
+		return fmt.Errorf("failed to update cached blog href: %w", err)
+	}
+	return nil
+}
+
+// GetBlogHrefChanges lists the blog URL changes with the given status, or
+// all of them when status is empty, oldest first.
+func (r *Repository) GetBlogHrefChanges(ctx context.Context, status string) ([]BlogHrefChange, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, blog_name, old_href, new_href, detected_at, status, resolved_at
+		FROM blog_href_changes
+		WHERE CAST(? AS TEXT) = '' OR status = ?
+		ORDER BY detected_at ASC, id ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, status, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blog href changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []BlogHrefChange
+	for rows.Next() {
+		var change BlogHrefChange
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&change.ID, &change.BlogName, &change.OldHref, &change.NewHref, &change.DetectedAt, &change.Status, &resolvedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blog href change row: %w", err)
+		}
+		if resolvedAt.Valid {
+			change.ResolvedAt = &resolvedAt.Time
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blog href change rows: %w", err)
+	}
+
+	return changes, nil
+}
+
+// RecordLayout stores the layout fingerprint of a successful scrape along
+// with its similarity to the previous one, flagging the blog when changed is
+// set. It must be called after RecordScrapeSuccess.
+func (r *Repository) RecordLayout(ctx context.Context, blogName string, fingerprint string, similarity float64, changed bool) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE scrape_status SET
+			layout_fingerprint = ?,
+			layout_similarity = ?,
+			layout_changed_at = CASE WHEN ? THEN ? ELSE layout_changed_at END
+		WHERE blog_name = ?
+	`
+	if _, err := r.db.ExecContext(ctx, query, fingerprint, similarity, changed, time.Now(), blogName); err != nil {
+		return fmt.Errorf("failed to record layout: %w", err)
+	}
+	return nil
+}
+
+// GetLayoutChanges returns the scrape status of the blogs flagged with a
+// layout change, the most recent first.
+func (r *Repository) GetLayoutChanges(ctx context.Context) ([]ScrapeStatus, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + scrapeStatusColumns + `
+		FROM scrape_status
+		WHERE layout_changed_at IS NOT NULL
+		ORDER BY layout_changed_at DESC, blog_name ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query layout changes: %w", err)
+	}
+	defer rows.Close()
+
+	var statuses []ScrapeStatus
+	for rows.Next() {
+		status, err := scanScrapeStatus(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan layout change row: %w", err)
+		}
+		statuses = append(statuses, status)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating layout change rows: %w", err)
+	}
+
+	return statuses, nil
+}
+
+// ClearLayoutChange removes the layout change flag of a blog once its
+// selectors were checked, along with its fingerprint so that the next scrape
+// starts a new baseline. It reports whether the blog was flagged.
+func (r *Repository) ClearLayoutChange(ctx context.Context, blogName string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE scrape_status SET
+			layout_fingerprint = '',
+			layout_similarity = NULL,
+			layout_changed_at = NULL
+		WHERE blog_name = ? AND layout_changed_at IS NOT NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, blogName)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear layout change: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to clear layout change: %w", err)
+	}
+	return n > 0, nil
+}
+
+// SavePageSnapshot stores a compressed copy of a fetched page and deletes the
+// oldest snapshots of the blog beyond the keep most recent ones.
+func (r *Repository) SavePageSnapshot(ctx context.Context, snapshot PageSnapshot, keep int) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(snapshot.Content); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+
+	fetchedAt := snapshot.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO page_snapshots (blog_name, page_url, fetched_at, content, matched_element)
+		VALUES (?, ?, ?, ?, ?)
+	`, snapshot.BlogName, snapshot.PageURL, fetchedAt, compressed.Bytes(), snapshot.MatchedElement)
+	if err != nil {
+		return fmt.Errorf("failed to insert snapshot: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM page_snapshots
+		WHERE blog_name = ? AND id NOT IN (
+			SELECT id FROM page_snapshots
+			WHERE blog_name = ?
+			ORDER BY fetched_at DESC, id DESC
+			LIMIT ?
+		)
+	`, snapshot.BlogName, snapshot.BlogName, keep)
+	if err != nil {
+		return fmt.Errorf("failed to prune snapshots: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetPageSnapshots returns the stored snapshots of a blog, most recent first,
+// with their content decompressed. A limit of 0 returns all of them.
+func (r *Repository) GetPageSnapshots(ctx context.Context, blogName string, limit int) ([]PageSnapshot, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, blog_name, page_url, fetched_at, content, matched_element
+		FROM page_snapshots
+		WHERE blog_name = ?
+		ORDER BY fetched_at DESC, id DESC
+	`
+	args := []any{blogName}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []PageSnapshot
+	for rows.Next() {
+		var snapshot PageSnapshot
+		var compressed []byte
+		if err := rows.Scan(&snapshot.ID, &snapshot.BlogName, &snapshot.PageURL, &snapshot.FetchedAt, &compressed, &snapshot.MatchedElement); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot row: %w", err)
+		}
+
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress snapshot %d: %w", snapshot.ID, err)
+		}
+		snapshot.Content, err = io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress snapshot %d: %w", snapshot.ID, err)
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating snapshot rows: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+func (r *Repository) InsertShadowResult(ctx context.Context, result ShadowResult) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	checkedAt := result.CheckedAt
+	if checkedAt.IsZero() {
+		checkedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO shadow_results (
+			blog_name, checked_at, live_name, live_href, live_error,
+			shadow_name, shadow_href, shadow_error, agreed
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		result.BlogName, checkedAt, result.LiveName, result.LiveHref, result.LiveError,
+		result.ShadowName, result.ShadowHref, result.ShadowError, result.Agreed,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert shadow result: %w", err)
+	}
+	return nil
+}
+
+// GetShadowStats summarizes the shadow results recorded since the given
+// time, per blog and ordered by blog name.
+func (r *Repository) GetShadowStats(ctx context.Context, since time.Time) ([]ShadowStats, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT blog_name, checked_at, live_name, live_href, live_error,
+			shadow_name, shadow_href, shadow_error, agreed
+		FROM shadow_results
+		WHERE checked_at >= ?
+		ORDER BY blog_name ASC, checked_at ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shadow results: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []ShadowStats
+	for rows.Next() {
+		var result ShadowResult
+		if err := rows.Scan(
+			&result.BlogName, &result.CheckedAt, &result.LiveName, &result.LiveHref, &result.LiveError,
+			&result.ShadowName, &result.ShadowHref, &result.ShadowError, &result.Agreed,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan shadow result row: %w", err)
+		}
+
+		if len(stats) == 0 || stats[len(stats)-1].BlogName != result.BlogName {
+			stats = append(stats, ShadowStats{BlogName: result.BlogName, FirstRunAt: result.CheckedAt})
+		}
+		current := &stats[len(stats)-1]
+
+		current.Runs++
+		current.LastRunAt = result.CheckedAt
+		if result.Agreed {
+			current.Agreements++
+		} else {
+			checkedAt := result.CheckedAt
+			current.LastDisagreementAt = &checkedAt
+			current.LastDisagreement = &ShadowDisagreement{
+				LiveName:    result.LiveName,
+				LiveHref:    result.LiveHref,
+				LiveError:   result.LiveError,
+				ShadowName:  result.ShadowName,
+				ShadowHref:  result.ShadowHref,
+				ShadowError: result.ShadowError,
+			}
+		}
+		current.AgreementRate = float64(current.Agreements) / float64(current.Runs)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shadow result rows: %w", err)
+	}
+
+	return stats, nil
+}
+
+// RecordLinkCheck stores the outcome of a link check, replacing the previous
+// check of the same link of the blog.
+func (r *Repository) RecordLinkCheck(ctx context.Context, check LinkCheck) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	checkedAt := check.CheckedAt
+	if checkedAt.IsZero() {
+		checkedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO link_checks (blog_name, link_kind, url, checked_at, status_code, status, detail)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(blog_name, link_kind) DO UPDATE SET
+			url = excluded.url,
+			checked_at = excluded.checked_at,
+			status_code = excluded.status_code,
+			status = excluded.status,
+			detail = excluded.detail
+	`
+	_, err := r.db.ExecContext(ctx, query, check.BlogName, string(check.Kind), check.URL, checkedAt, check.StatusCode, string(check.Status), check.Detail)
+	if err != nil {
+		return fmt.Errorf("failed to record link check: %w", err)
+	}
+	return nil
+}
+
+// GetLinkChecks returns the latest link checks, ordered by blog and link
+// kind. With brokenOnly, only the links found broken are returned.
+func (r *Repository) GetLinkChecks(ctx context.Context, brokenOnly bool) ([]LinkCheck, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT blog_name, link_kind, url, checked_at, status_code, status, detail
+		FROM link_checks
+		WHERE NOT ? OR status NOT IN ('ok', 'error')
+		ORDER BY blog_name ASC, link_kind ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query, brokenOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query link checks: %w", err)
+	}
+	defer rows.Close()
+
+	var checks []LinkCheck
+	for rows.Next() {
+		var check LinkCheck
+		var kind, status string
+		if err := rows.Scan(&check.BlogName, &kind, &check.URL, &check.CheckedAt, &check.StatusCode, &status, &check.Detail); err != nil {
+			return nil, fmt.Errorf("failed to scan link check row: %w", err)
+		}
+		check.Kind = LinkKind(kind)
+		check.Status = LinkStatus(status)
+		checks = append(checks, check)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating link check rows: %w", err)
+	}
+
+	return checks, nil
+}
+
+// GetBlogIcon returns the icon of a blog, or nil when it has none.
+func (r *Repository) GetBlogIcon(ctx context.Context, blogName string) (*BlogIcon, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT blog_name, source_url, content, content_hash, fetched_at
+		FROM blog_icons
+		WHERE blog_name = ?
+	`
+	var icon BlogIcon
+	err := r.db.QueryRowContext(ctx, query, blogName).Scan(&icon.BlogName, &icon.SourceURL, &icon.Content, &icon.ContentHash, &icon.FetchedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blog icon: %w", err)
+	}
+	return &icon, nil
+}
+
+// SaveBlogIcon stores the icon of a blog, replacing the previous one.
+func (r *Repository) SaveBlogIcon(ctx context.Context, icon BlogIcon) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	fetchedAt := icon.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO blog_icons (blog_name, source_url, content, content_hash, fetched_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(blog_name) DO UPDATE SET
+			source_url = excluded.source_url,
+			content = excluded.content,
+			content_hash = excluded.content_hash,
+			fetched_at = excluded.fetched_at
+	`
+	if _, err := r.db.ExecContext(ctx, query, icon.BlogName, icon.SourceURL, icon.Content, icon.ContentHash, fetchedAt); err != nil {
+		return fmt.Errorf("failed to save blog icon: %w", err)
+	}
+	return nil
+}
+
+// SaveGitHubProfile stores the GitHub profile of a blog, replacing the
+// previous one.
+func (r *Repository) SaveGitHubProfile(ctx context.Context, blogName string, profile GitHubProfile) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	topRepos, err := json.Marshal(profile.TopRepos)
+	if err != nil {
+		return fmt.Errorf("failed to encode top repos: %w", err)
+	}
+	if profile.TopRepos == nil {
+		topRepos = []byte("[]")
+	}
+	fetchedAt := profile.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO github_profiles (blog_name, login, name, bio, followers, top_repos, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(blog_name) DO UPDATE SET
+			login = excluded.login,
+			name = excluded.name,
+			bio = excluded.bio,
+			followers = excluded.followers,
+			top_repos = excluded.top_repos,
+			fetched_at = excluded.fetched_at
+	`
+	if _, err := r.db.ExecContext(ctx, query, blogName, profile.Login, profile.Name, profile.Bio, profile.Followers, string(topRepos), fetchedAt); err != nil {
+		return fmt.Errorf("failed to save GitHub profile: %w", err)
+	}
+	return nil
+}
+
+// GetGitHubProfiles returns the stored GitHub profiles, keyed by blog name.
+func (r *Repository) GetGitHubProfiles(ctx context.Context) (map[string]GitHubProfile, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT blog_name, login, name, bio, followers, top_repos, fetched_at
+		FROM github_profiles
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query GitHub profiles: %w", err)
+	}
+	defer rows.Close()
+
+	profiles := make(map[string]GitHubProfile)
+	for rows.Next() {
+		var blogName, topRepos string
+		var profile GitHubProfile
+		if err := rows.Scan(&blogName, &profile.Login, &profile.Name, &profile.Bio, &profile.Followers, &topRepos, &profile.FetchedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan GitHub profile: %w", err)
+		}
+		if err := json.Unmarshal([]byte(topRepos), &profile.TopRepos); err != nil {
+			return nil, fmt.Errorf("failed to decode top repos of %s: %w", blogName, err)
+		}
+		profiles[blogName] = profile
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating GitHub profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// attachTags sets the tags of the blogs, sorted by name.
+func (r *Repository) attachTags(ctx context.Context, items []BlogInfo) error {
+	if len(items) == 0 {
+		return nil
+	}
+	tags, err := r.getBlogTags(ctx, "")
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].Tags = tags[items[i].BlogName]
+	}
+	return nil
+}
+
+// attachGitHubProfiles sets the GitHub profile of the blogs that have one.
+func (r *Repository) attachGitHubProfiles(ctx context.Context, items []BlogInfo) error {
+	if len(items) == 0 {
+		return nil
+	}
+	profiles, err := r.GetGitHubProfiles(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if profile, ok := profiles[items[i].BlogName]; ok {
+			items[i].GitHub = &profile
+		}
+	}
+	return nil
+}
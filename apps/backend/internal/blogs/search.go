@@ -0,0 +1,89 @@
+package blogs
+
+import (
+	"html"
+	"html/template"
+	"strings"
+	"unicode"
+)
+
+// SearchKind tells whether a search result is a blog or an article.
+type SearchKind string
+
+const (
+	SearchBlog    SearchKind = "blog"
+	SearchArticle SearchKind = "article"
+)
+
+// SearchResult is a blog or an article matching a search.
+type SearchResult struct {
+	Kind     SearchKind `json:"kind"`
+	BlogName string     `json:"blogName"`
+	// Title is the blog name or the article title, and Href the blog or
+	// article URL.
+	Title string `json:"title"`
+	Href  string `json:"href"`
+	// TitleHTML and SnippetHTML are the title and, for blogs, an excerpt of
+	// the description, HTML-escaped with the matched words in <mark>.
+	TitleHTML   template.HTML `json:"titleHtml"`
+	SnippetHTML template.HTML `json:"snippetHtml,omitempty"`
+}
+
+// maxSearchTerms bounds the number of words a search looks for.
+const maxSearchTerms = 8
+
+// The databases wrap matched words in these private use characters, which
+// blog names and titles do not contain, so that the text can be escaped
+// before they become <mark> elements.
+const (
+	searchMarkStart = "\ue000"
+	searchMarkEnd   = "\ue001"
+)
+
+// searchTerms splits a search into the words it looks for, lower-cased and
+// without punctuation.
+func searchTerms(query string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// ftsQuery returns the FTS5 query matching every term, the last one as a
+// prefix since it may still be being typed.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"`
+	}
+	return strings.Join(quoted, " ") + "*"
+}
+
+// tsQuery returns the PostgreSQL tsquery matching every term, the last one
+// as a prefix.
+func tsQuery(terms []string) string {
+	return strings.Join(terms, " & ") + ":*"
+}
+
+// newSearchResult builds a result from the highlighted title and snippet the
+// database returned.
+func newSearchResult(kind SearchKind, blogName, href, title, snippet string) SearchResult {
+	return SearchResult{
+		Kind:        kind,
+		BlogName:    blogName,
+		Title:       strings.NewReplacer(searchMarkStart, "", searchMarkEnd, "").Replace(title),
+		Href:        href,
+		TitleHTML:   highlightHTML(title),
+		SnippetHTML: highlightHTML(snippet),
+	}
+}
+
+// highlightHTML escapes highlighted text, turning its marks into <mark>
+// elements.
+func highlightHTML(text string) template.HTML {
+	escaped := html.EscapeString(text)
+	return template.HTML(strings.NewReplacer(searchMarkStart, "<mark>", searchMarkEnd, "</mark>").Replace(escaped))
+}
@@ -0,0 +1,90 @@
+package blogs
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// sqlDB runs the repository queries, written with ? placeholders, on a
+// database whose driver may number its placeholders instead.
+type sqlDB struct {
+	*sql.DB
+	// numbered is set for drivers expecting $1, $2... placeholders.
+	numbered bool
+}
+
+func (db *sqlDB) rebind(query string) string {
+	if !db.numbered {
+		return query
+	}
+	return numberPlaceholders(query)
+}
+
+func (db *sqlDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.rebind(query), args...)
+}
+
+func (db *sqlDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return db.DB.QueryContext(ctx, db.rebind(query), args...)
+}
+
+func (db *sqlDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.rebind(query), args...)
+}
+
+func (db *sqlDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sqlTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, db: db}, nil
+}
+
+// sqlTx is a transaction of a sqlDB, rebinding its queries the same way.
+type sqlTx struct {
+	*sql.Tx
+	db *sqlDB
+}
+
+func (tx *sqlTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return tx.Tx.ExecContext(ctx, tx.db.rebind(query), args...)
+}
+
+func (tx *sqlTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return tx.Tx.QueryRowContext(ctx, tx.db.rebind(query), args...)
+}
+
+func (tx *sqlTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return tx.Tx.PrepareContext(ctx, tx.db.rebind(query))
+}
+
+// numberPlaceholders turns the ? placeholders of a query into $1, $2...,
+// leaving question marks in string literals and quoted identifiers alone.
+func numberPlaceholders(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			// A doubled quote escapes itself and keeps the literal open
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
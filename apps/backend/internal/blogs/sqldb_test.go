@@ -0,0 +1,25 @@
+package blogs
+
+import "testing"
+
+func TestNumberPlaceholders(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"none", "SELECT 1", "SELECT 1"},
+		{"in order", "UPDATE t SET a = ?, b = ? WHERE c = ?", "UPDATE t SET a = $1, b = $2 WHERE c = $3"},
+		{"string literal", "SELECT '?' WHERE a = ?", "SELECT '?' WHERE a = $1"},
+		{"escaped quote", "SELECT 'it''s ?' WHERE a = ?", "SELECT 'it''s ?' WHERE a = $1"},
+		{"quoted identifier", `SELECT "a?" FROM t WHERE b = ?`, `SELECT "a?" FROM t WHERE b = $1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := numberPlaceholders(tt.query); got != tt.want {
+				t.Errorf("numberPlaceholders(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package blogs
+
+import (
+	"context"
+	"time"
+)
+
+// Store persists the blogs, their configuration and everything the scraper
+// records about them. Repository implements it on SQLite and PostgreSQL.
+type Store interface {
+	GetAllBlogs(ctx context.Context) ([]BlogInfo, error)
+	GetBlogsByKind(ctx context.Context, kind Kind) ([]BlogInfo, error)
+	GetBlogCache(ctx context.Context, blogName string) (*BlogInfo, error)
+	UpsertBlogCache(ctx context.Context, blog BlogInfo) error
+	UpdateBlogMetadata(ctx context.Context, blogName string, metadata BlogMetadata) error
+	RecordNewArticle(ctx context.Context, blogName string) error
+
+	GetAllBlogConfigs(ctx context.Context) ([]BlogConfig, error)
+	GetBlogConfig(ctx context.Context, blogName string) (*BlogConfig, error)
+	SyncBlogConfigs(ctx context.Context, upserts []BlogConfig, deletes []string) error
+	SyncDirectory(ctx context.Context, upserts []BlogConfig, deletes []string, tagUpserts []Tag, tagDeletes []string) error
+	SetBlogDisabled(ctx context.Context, blogName string, disabled bool) (bool, error)
+	ClearBlogCache(ctx context.Context, blogName string) (int64, error)
+
+	GetTags(ctx context.Context) ([]Tag, error)
+
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
+	SuggestBlogNames(ctx context.Context, prefix string, limit int) ([]string, error)
+
+	InsertArticles(ctx context.Context, blogName string, articles []Article) (int, error)
+
+	RecordScrapeSuccess(ctx context.Context, blogName string) error
+	RecordScrapeFailure(ctx context.Context, blogName string, reason string) error
+	GetScrapeStatus(ctx context.Context, blogName string) (*ScrapeStatus, error)
+	RecordLayout(ctx context.Context, blogName string, fingerprint string, similarity float64, changed bool) error
+	GetLayoutChanges(ctx context.Context) ([]ScrapeStatus, error)
+	ClearLayoutChange(ctx context.Context, blogName string) (bool, error)
+
+	RecordRedirects(ctx context.Context, blogName string, finalURL string, chain []Redirect, permanent bool) error
+	QueueBlogHrefChange(ctx context.Context, blogName string, oldHref string, newHref string) (bool, error)
+	ApplyBlogHrefChange(ctx context.Context, blogName string, oldHref string, newHref string) error
+	ResolveBlogHrefChange(ctx context.Context, id int64, approve bool) (*BlogHrefChange, error)
+	GetBlogHrefChanges(ctx context.Context, status string) ([]BlogHrefChange, error)
+
+	SavePageSnapshot(ctx context.Context, snapshot PageSnapshot, keep int) error
+	GetPageSnapshots(ctx context.Context, blogName string, limit int) ([]PageSnapshot, error)
+
+	InsertShadowResult(ctx context.Context, result ShadowResult) error
+	GetShadowStats(ctx context.Context, since time.Time) ([]ShadowStats, error)
+
+	RecordLinkCheck(ctx context.Context, check LinkCheck) error
+	GetLinkChecks(ctx context.Context, brokenOnly bool) ([]LinkCheck, error)
+
+	GetBlogIcon(ctx context.Context, blogName string) (*BlogIcon, error)
+	SaveBlogIcon(ctx context.Context, icon BlogIcon) error
+
+	SaveGitHubProfile(ctx context.Context, blogName string, profile GitHubProfile) error
+	GetGitHubProfiles(ctx context.Context) (map[string]GitHubProfile, error)
+}
+
+var _ Store = (*Repository)(nil)
@@ -0,0 +1,929 @@
+package blogs_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+	"github.com/nesco/techblogs/backend/internal/database"
+	"github.com/nesco/techblogs/backend/migrations"
+)
+
+// storeBackend opens an empty, fully migrated database and a Store on it.
+type storeBackend struct {
+	name string
+	open func(t *testing.T) (*sql.DB, blogs.Store)
+}
+
+var storeBackends = []storeBackend{
+	{"sqlite", openSQLiteStore},
+	{"postgres", openPostgresStore},
+}
+
+// openSQLiteStore migrates a SQLite database in a temporary file; an
+// in-memory database would be different for each pooled connection.
+func openSQLiteStore(t *testing.T) (*sql.DB, blogs.Store) {
+	t.Helper()
+
+	db, err := database.InitDB(filepath.Join(t.TempDir(), "techblogs.db"))
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	applyMigrations(t, db, migrations.SQLite)
+	return db, blogs.NewRepository(db)
+}
+
+// openPostgresStore migrates a fresh schema of the PostgreSQL database at
+// TEST_POSTGRES_DSN, dropped after the test. The test is skipped when the
+// variable is not set.
+func openPostgresStore(t *testing.T) (*sql.DB, blogs.Store) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+
+	admin, err := database.InitPostgres(dsn)
+	if err != nil {
+		t.Fatalf("failed to open PostgreSQL database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	schema := "techblogs_test_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+	})
+
+	db, err := database.InitPostgres(withSearchPath(t, dsn, schema))
+	if err != nil {
+		t.Fatalf("failed to open PostgreSQL schema: %v", err)
+	}
+	// Registered after the schema cleanup, so it runs first
+	t.Cleanup(func() { db.Close() })
+
+	applyMigrations(t, db, migrations.Postgres)
+	return db, blogs.NewPostgresRepository(db)
+}
+
+// withSearchPath makes a DSN, URL or key=value pairs, use the given schema.
+func withSearchPath(t *testing.T, dsn string, schema string) string {
+	t.Helper()
+
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " search_path=" + schema
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("invalid TEST_POSTGRES_DSN: %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// applyMigrations migrates the schema, then removes the seeded blogs so that
+// tests start from an empty database.
+func applyMigrations(t *testing.T, db *sql.DB, schema fs.FS) {
+	t.Helper()
+
+	migrator, err := database.NewMigrator(db, schema)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	if _, err := db.Exec("DELETE FROM blog_configs"); err != nil {
+		t.Fatalf("failed to remove seeded blogs: %v", err)
+	}
+}
+
+// addBlog configures a blog with selectors only, straight in the database.
+func addBlog(t *testing.T, db *sql.DB, name string, kind blogs.Kind) {
+	t.Helper()
+
+	quoted := strings.ReplaceAll(name, "'", "''")
+	_, err := db.Exec(fmt.Sprintf(`
+		INSERT INTO blog_configs (blog_name, blog_href, kind, article_href_selector, article_name_selector)
+		VALUES ('%s', '%s', '%s', 'article a', 'article h2')
+	`, quoted, blogHref(quoted), kind))
+	if err != nil {
+		t.Fatalf("failed to add blog %s: %v", name, err)
+	}
+}
+
+func blogHref(name string) string {
+	return "https://" + strings.ToLower(strings.ReplaceAll(name, " ", "-")) + ".example/"
+}
+
+func cacheBlog(t *testing.T, store blogs.Store, name string, kind blogs.Kind) {
+	t.Helper()
+
+	err := store.UpsertBlogCache(context.Background(), blogs.BlogInfo{
+		BlogName:          name,
+		BlogHref:          blogHref(name),
+		LatestArticleName: "Latest of " + name,
+		LatestArticleHref: blogHref(name) + "latest",
+		Kind:              kind,
+	})
+	if err != nil {
+		t.Fatalf("UpsertBlogCache(%s) error = %v", name, err)
+	}
+}
+
+func assertRecent(t *testing.T, name string, got time.Time) {
+	t.Helper()
+	if d := time.Since(got); d < -time.Second || d > time.Minute {
+		t.Errorf("%s = %v, want about now", name, got)
+	}
+}
+
+// TestStore is the conformance suite every Store implementation must pass.
+func TestStore(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, db *sql.DB, store blogs.Store)
+	}{
+		{"BlogConfigs", testBlogConfigs},
+		{"SyncBlogConfigs", testSyncBlogConfigs},
+		{"Tags", testTags},
+		{"DisabledBlogs", testDisabledBlogs},
+		{"Search", testSearch},
+		{"ClearBlogCache", testClearBlogCache},
+		{"BlogCache", testBlogCache},
+		{"Articles", testArticles},
+		{"ScrapeStatus", testScrapeStatus},
+		{"BlogHrefChanges", testBlogHrefChanges},
+		{"PageSnapshots", testPageSnapshots},
+		{"ShadowResults", testShadowResults},
+		{"LinkChecks", testLinkChecks},
+		{"BlogIcons", testBlogIcons},
+		{"GitHubProfiles", testGitHubProfiles},
+	}
+
+	for _, backend := range storeBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					db, store := backend.open(t)
+					tt.run(t, db, store)
+				})
+			}
+		})
+	}
+}
+
+func testBlogConfigs(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Beta", blogs.Organization)
+	addBlog(t, db, "Alpha", blogs.Individual)
+	if _, err := db.Exec("UPDATE blog_configs SET archived = TRUE WHERE blog_name = 'Beta'"); err != nil {
+		t.Fatalf("failed to archive blog: %v", err)
+	}
+
+	configs, err := store.GetAllBlogConfigs(ctx)
+	if err != nil {
+		t.Fatalf("GetAllBlogConfigs() error = %v", err)
+	}
+	if len(configs) != 2 || configs[0].BlogName != "Alpha" || configs[1].BlogName != "Beta" {
+		t.Fatalf("GetAllBlogConfigs() = %+v, want Alpha then Beta", configs)
+	}
+	if configs[0].Kind != blogs.Individual || configs[0].ArticleHrefSelector != "article a" || configs[0].Archived {
+		t.Errorf("Alpha config = %+v", configs[0])
+	}
+	if !configs[1].Archived {
+		t.Error("expected Beta to be archived")
+	}
+
+	config, err := store.GetBlogConfig(ctx, "Alpha")
+	if err != nil || config == nil || config.BlogHref != blogHref("Alpha") {
+		t.Errorf("GetBlogConfig(Alpha) = %+v, %v", config, err)
+	}
+	if config, err := store.GetBlogConfig(ctx, "Missing"); config != nil || err != nil {
+		t.Errorf("GetBlogConfig(Missing) = %+v, %v, want nil, nil", config, err)
+	}
+}
+
+func testSyncBlogConfigs(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+	addBlog(t, db, "Beta", blogs.Organization)
+	cacheBlog(t, store, "Alpha", blogs.Individual)
+	cacheBlog(t, store, "Beta", blogs.Organization)
+
+	alpha := blogs.BlogConfig{
+		BlogName:   "Alpha",
+		BlogHref:   "https://alpha.example/blog/",
+		Kind:       blogs.Organization,
+		GitHubHref: "https://github.com/alpha",
+		FeedHref:   "https://alpha.example/feed.xml",
+		Platform:   blogs.PlatformFeed,
+		Rules:      blogs.ValidationRules{TitleBlocklist: []string{"Blog"}},
+	}
+	gamma := blogs.BlogConfig{
+		BlogName:        "Gamma",
+		BlogHref:        blogHref("Gamma"),
+		Kind:            blogs.Individual,
+		PageURLTemplate: "https://gamma.example/page/{page}",
+		HrefRule:        &blogs.ExtractionRule{Engine: blogs.EngineXPath, Selector: "//article//a", Attribute: "href"},
+		NameRule:        &blogs.ExtractionRule{Selector: "article h2", Attribute: "text"},
+		Shadow:          &blogs.ExtractionStrategy{Platform: blogs.PlatformWordPress},
+		Archived:        true,
+	}
+	if err := store.SyncBlogConfigs(ctx, []blogs.BlogConfig{alpha, gamma}, []string{"Beta"}); err != nil {
+		t.Fatalf("SyncBlogConfigs() error = %v", err)
+	}
+
+	configs, err := store.GetAllBlogConfigs(ctx)
+	if err != nil {
+		t.Fatalf("GetAllBlogConfigs() error = %v", err)
+	}
+	if len(configs) != 2 {
+		t.Fatalf("GetAllBlogConfigs() = %+v, want Alpha and Gamma", configs)
+	}
+	// The upsert replaces the whole configuration, selectors included
+	for i, want := range []blogs.BlogConfig{alpha, gamma} {
+		if got := configs[i]; !reflect.DeepEqual(got, want) {
+			t.Errorf("config %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	cache, err := store.GetBlogCache(ctx, "Alpha")
+	if err != nil || cache == nil {
+		t.Fatalf("GetBlogCache(Alpha) = %+v, %v", cache, err)
+	}
+	if cache.BlogHref != alpha.BlogHref || cache.Kind != alpha.Kind || cache.GitHubHref != alpha.GitHubHref {
+		t.Errorf("Alpha cache = %+v, want the synced href, kind and GitHub link", cache)
+	}
+	if cache, err := store.GetBlogCache(ctx, "Beta"); cache != nil || err != nil {
+		t.Errorf("GetBlogCache(Beta) = %+v, %v, want the delete to cascade", cache, err)
+	}
+}
+
+func testTags(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	for _, name := range []string{"Alpha", "Beta", "Gamma"} {
+		addBlog(t, db, name, blogs.Individual)
+	}
+	cacheBlog(t, store, "Alpha", blogs.Individual)
+	cacheBlog(t, store, "Beta", blogs.Individual)
+
+	configs, err := store.GetAllBlogConfigs(ctx)
+	if err != nil {
+		t.Fatalf("GetAllBlogConfigs() error = %v", err)
+	}
+	configs[0].Tags = []string{"databases", "systems"}
+	configs[1].Tags = []string{"databases"}
+	configs[2].Tags = []string{"databases"}
+	if err := store.SyncDirectory(ctx, configs, nil, []blogs.Tag{{Name: "databases", Description: "Storage engines"}, {Name: "security"}}, nil); err != nil {
+		t.Fatalf("SyncDirectory() error = %v", err)
+	}
+
+	config, err := store.GetBlogConfig(ctx, "Alpha")
+	if err != nil || config == nil || !slices.Equal(config.Tags, []string{"databases", "systems"}) {
+		t.Errorf("GetBlogConfig(Alpha) = %+v, %v, want its tags", config, err)
+	}
+	all, err := store.GetAllBlogs(ctx)
+	if err != nil || len(all) != 2 || !slices.Equal(all[1].Tags, []string{"databases"}) {
+		t.Errorf("GetAllBlogs() = %+v, %v, want the tags attached", all, err)
+	}
+
+	// Gamma is not cached, and disabled blogs are not shown
+	if _, err := store.SetBlogDisabled(ctx, "Beta", true); err != nil {
+		t.Fatalf("SetBlogDisabled(Beta) error = %v", err)
+	}
+	tags, err := store.GetTags(ctx)
+	if err != nil {
+		t.Fatalf("GetTags() error = %v", err)
+	}
+	want := []blogs.Tag{{Name: "databases", Description: "Storage engines", Blogs: 1}, {Name: "security"}, {Name: "systems", Blogs: 1}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("GetTags() = %+v, want %+v", tags, want)
+	}
+
+	// Nor are blogs outside the default lifecycle states
+	if _, err := db.Exec("UPDATE blog_configs SET archived = TRUE WHERE blog_name = 'Alpha'"); err != nil {
+		t.Fatalf("failed to archive Alpha: %v", err)
+	}
+	tags, err = store.GetTags(ctx)
+	if err != nil {
+		t.Fatalf("GetTags() error = %v", err)
+	}
+	want = []blogs.Tag{{Name: "databases", Description: "Storage engines"}, {Name: "security"}, {Name: "systems"}}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("GetTags() = %+v, want %+v once Alpha is archived", tags, want)
+	}
+	if _, err := db.Exec("UPDATE blog_configs SET archived = FALSE WHERE blog_name = 'Alpha'"); err != nil {
+		t.Fatalf("failed to unarchive Alpha: %v", err)
+	}
+
+	// Deleting a tag removes it from its blogs, and the next sync replaces
+	// a blog's tags
+	if err := store.SyncDirectory(ctx, nil, nil, nil, []string{"systems"}); err != nil {
+		t.Fatalf("SyncDirectory() error = %v", err)
+	}
+	configs[1].Tags = nil
+	if err := store.SyncBlogConfigs(ctx, configs[1:2], nil); err != nil {
+		t.Fatalf("SyncBlogConfigs() error = %v", err)
+	}
+	configs, err = store.GetAllBlogConfigs(ctx)
+	if err != nil {
+		t.Fatalf("GetAllBlogConfigs() error = %v", err)
+	}
+	for i, want := range [][]string{{"databases"}, nil, {"databases"}} {
+		if !slices.Equal(configs[i].Tags, want) {
+			t.Errorf("%s tags = %v, want %v", configs[i].BlogName, configs[i].Tags, want)
+		}
+	}
+}
+
+func testDisabledBlogs(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+	addBlog(t, db, "Beta", blogs.Individual)
+	cacheBlog(t, store, "Alpha", blogs.Individual)
+	cacheBlog(t, store, "Beta", blogs.Individual)
+
+	found, err := store.SetBlogDisabled(ctx, "Beta", true)
+	if err != nil || !found {
+		t.Fatalf("SetBlogDisabled(Beta) = %v, %v", found, err)
+	}
+	if found, err := store.SetBlogDisabled(ctx, "Missing", true); found || err != nil {
+		t.Errorf("SetBlogDisabled(Missing) = %v, %v, want false, nil", found, err)
+	}
+
+	shown := func() []string {
+		t.Helper()
+		all, err := store.GetAllBlogs(ctx)
+		if err != nil {
+			t.Fatalf("GetAllBlogs() error = %v", err)
+		}
+		byKind, err := store.GetBlogsByKind(ctx, blogs.Individual)
+		if err != nil {
+			t.Fatalf("GetBlogsByKind() error = %v", err)
+		}
+		if len(all) != len(byKind) {
+			t.Errorf("GetAllBlogs() and GetBlogsByKind() returned %d and %d blogs", len(all), len(byKind))
+		}
+		var names []string
+		for _, blog := range all {
+			names = append(names, blog.BlogName)
+		}
+		return names
+	}
+	if names := shown(); !slices.Equal(names, []string{"Alpha"}) {
+		t.Errorf("shown blogs = %v, want the disabled one hidden", names)
+	}
+
+	// Syncing the directory file keeps the blog disabled
+	config, err := store.GetBlogConfig(ctx, "Beta")
+	if err != nil || config == nil || !config.Disabled {
+		t.Fatalf("GetBlogConfig(Beta) = %+v, %v, want it disabled", config, err)
+	}
+	config.Disabled = false
+	if err := store.SyncBlogConfigs(ctx, []blogs.BlogConfig{*config}, nil); err != nil {
+		t.Fatalf("SyncBlogConfigs() error = %v", err)
+	}
+	if config, _ := store.GetBlogConfig(ctx, "Beta"); config == nil || !config.Disabled {
+		t.Errorf("GetBlogConfig(Beta) = %+v after a sync, want it still disabled", config)
+	}
+
+	if _, err := store.SetBlogDisabled(ctx, "Beta", false); err != nil {
+		t.Fatalf("SetBlogDisabled(Beta, false) error = %v", err)
+	}
+	if names := shown(); !slices.Equal(names, []string{"Alpha", "Beta"}) {
+		t.Errorf("shown blogs = %v, want both once enabled", names)
+	}
+}
+
+func testSearch(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	const longTitle = "A title long enough to be cut into a snippet, since it goes on and on well past sixteen words before its epilogue"
+	for _, name := range []string{"Alpha", "Beta", "Gamma", "Storage Weekly"} {
+		addBlog(t, db, name, blogs.Individual)
+		cacheBlog(t, store, name, blogs.Individual)
+	}
+	if err := store.UpdateBlogMetadata(ctx, "Alpha", blogs.BlogMetadata{Description: "Notes on storage engines"}); err != nil {
+		t.Fatalf("UpdateBlogMetadata() error = %v", err)
+	}
+	articles := map[string][]blogs.Article{
+		"Alpha": {{Name: "Trees & LSM trees", Href: "https://alpha.example/trees"}, {Name: "Cooking pasta", Href: "https://alpha.example/pasta"}},
+		"Beta":  {{Name: "Trees everywhere", Href: "https://beta.example/trees"}},
+		"Gamma": {{Name: longTitle, Href: "https://gamma.example/long"}},
+	}
+	for name, list := range articles {
+		if _, err := store.InsertArticles(ctx, name, list); err != nil {
+			t.Fatalf("InsertArticles(%s) error = %v", name, err)
+		}
+	}
+	if _, err := store.SetBlogDisabled(ctx, "Beta", true); err != nil {
+		t.Fatalf("SetBlogDisabled() error = %v", err)
+	}
+
+	search := func(query string, limit int) []blogs.SearchResult {
+		t.Helper()
+		results, err := store.Search(ctx, query, limit)
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", query, err)
+		}
+		return results
+	}
+
+	// Names weigh more than descriptions
+	results := search("storage", 10)
+	if len(results) != 2 || results[0].BlogName != "Storage Weekly" || results[1].BlogName != "Alpha" {
+		t.Fatalf("Search(storage) = %+v, want Storage Weekly then Alpha", results)
+	}
+	if got := results[0]; got.Kind != blogs.SearchBlog || got.Title != "Storage Weekly" || got.TitleHTML != "<mark>Storage</mark> Weekly" || got.Href != blogHref("Storage Weekly") {
+		t.Errorf("Storage Weekly result = %+v", got)
+	}
+	if got := results[1].SnippetHTML; got != "Notes on <mark>storage</mark> engines" {
+		t.Errorf("Alpha snippet = %q", got)
+	}
+	if results := search("storage", 1); len(results) != 1 {
+		t.Errorf("Search(storage, 1) = %+v, want a single result", results)
+	}
+
+	// Articles of disabled blogs are left out, and the last word is a prefix
+	results = search("TREE", 10)
+	if len(results) != 1 {
+		t.Fatalf("Search(TREE) = %+v, want the article of Alpha", results)
+	}
+	if got := results[0]; got.Kind != blogs.SearchArticle || got.BlogName != "Alpha" || got.Href != "https://alpha.example/trees" ||
+		got.Title != "Trees & LSM trees" || got.TitleHTML != "<mark>Trees</mark> &amp; LSM <mark>trees</mark>" || got.SnippetHTML != "" {
+		t.Errorf("Trees result = %+v", got)
+	}
+	if results := search("pasta, cook", 10); len(results) != 1 || results[0].Title != "Cooking pasta" {
+		t.Errorf("Search(pasta, cook) = %+v, want Cooking pasta", results)
+	}
+	// Titles are never cut into snippets
+	results = search("epilogue", 10)
+	if len(results) != 1 || results[0].Title != longTitle || string(results[0].TitleHTML) != strings.Replace(longTitle, "epilogue", "<mark>epilogue</mark>", 1) {
+		t.Errorf("Search(epilogue) = %+v, want the whole long title", results)
+	}
+	for _, query := range []string{"", " !? ", "pasta trees"} {
+		if results := search(query, 10); len(results) != 0 {
+			t.Errorf("Search(%q) = %+v, want none", query, results)
+		}
+	}
+
+	names, err := store.SuggestBlogNames(ctx, "A", 10)
+	if err != nil {
+		t.Fatalf("SuggestBlogNames() error = %v", err)
+	}
+	if want := []string{"Alpha", "Gamma", "Storage Weekly"}; !slices.Equal(names, want) {
+		t.Errorf("SuggestBlogNames(A) = %q, want %q", names, want)
+	}
+	for prefix, want := range map[string][]string{"st": {"Storage Weekly"}, "_": nil, " ": nil} {
+		names, err := store.SuggestBlogNames(ctx, prefix, 10)
+		if err != nil || !slices.Equal(names, want) {
+			t.Errorf("SuggestBlogNames(%q) = %q, %v, want %q", prefix, names, err, want)
+		}
+	}
+
+	// The index follows the blogs and articles
+	if err := store.UpdateBlogMetadata(ctx, "Alpha", blogs.BlogMetadata{Description: "Notes on databases"}); err != nil {
+		t.Fatalf("UpdateBlogMetadata() error = %v", err)
+	}
+	if results := search("storage", 10); len(results) != 1 {
+		t.Errorf("Search(storage) = %+v, want the new description indexed", results)
+	}
+	if err := store.SyncBlogConfigs(ctx, nil, []string{"Alpha"}); err != nil {
+		t.Fatalf("SyncBlogConfigs() error = %v", err)
+	}
+	for _, query := range []string{"notes", "pasta"} {
+		if results := search(query, 10); len(results) != 0 {
+			t.Errorf("Search(%q) = %+v, want the deleted blog and articles unindexed", query, results)
+		}
+	}
+}
+
+func testClearBlogCache(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	for _, name := range []string{"Alpha", "Beta", "Gamma"} {
+		addBlog(t, db, name, blogs.Organization)
+		cacheBlog(t, store, name, blogs.Organization)
+	}
+
+	cleared, err := store.ClearBlogCache(ctx, "Alpha")
+	if err != nil || cleared != 1 {
+		t.Fatalf("ClearBlogCache(Alpha) = %d, %v, want 1", cleared, err)
+	}
+	if cache, err := store.GetBlogCache(ctx, "Alpha"); cache != nil || err != nil {
+		t.Errorf("GetBlogCache(Alpha) = %+v, %v, want nil, nil", cache, err)
+	}
+	if config, _ := store.GetBlogConfig(ctx, "Alpha"); config == nil {
+		t.Error("expected the configuration to be kept")
+	}
+
+	cleared, err = store.ClearBlogCache(ctx, "")
+	if err != nil || cleared != 2 {
+		t.Errorf("ClearBlogCache() = %d, %v, want 2", cleared, err)
+	}
+}
+
+func testBlogCache(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+	addBlog(t, db, "Beta", blogs.Organization)
+	cacheBlog(t, store, "Alpha", blogs.Individual)
+	cacheBlog(t, store, "Beta", blogs.Organization)
+
+	all, err := store.GetAllBlogs(ctx)
+	if err != nil {
+		t.Fatalf("GetAllBlogs() error = %v", err)
+	}
+	if len(all) != 2 || all[0].BlogName != "Alpha" || all[1].BlogName != "Beta" {
+		t.Fatalf("GetAllBlogs() = %+v, want Alpha then Beta", all)
+	}
+
+	people, err := store.GetBlogsByKind(ctx, blogs.Individual)
+	if err != nil {
+		t.Fatalf("GetBlogsByKind() error = %v", err)
+	}
+	if len(people) != 1 || people[0].BlogName != "Alpha" || people[0].Kind != blogs.Individual {
+		t.Fatalf("GetBlogsByKind(individual) = %+v, want Alpha", people)
+	}
+
+	if blog, err := store.GetBlogCache(ctx, "Missing"); blog != nil || err != nil {
+		t.Errorf("GetBlogCache(Missing) = %+v, %v, want nil, nil", blog, err)
+	}
+
+	metadata := blogs.BlogMetadata{Description: "Notes", SiteName: "Alpha's", Lang: "en-us", FaviconHref: "https://alpha.example/icon.png", FeedHref: "https://alpha.example/feed.xml"}
+	if err := store.UpdateBlogMetadata(ctx, "Alpha", metadata); err != nil {
+		t.Fatalf("UpdateBlogMetadata() error = %v", err)
+	}
+	if err := store.RecordNewArticle(ctx, "Alpha"); err != nil {
+		t.Fatalf("RecordNewArticle() error = %v", err)
+	}
+	if _, err := db.Exec("UPDATE blog_configs SET archived = TRUE WHERE blog_name = 'Beta'"); err != nil {
+		t.Fatalf("failed to archive blog: %v", err)
+	}
+
+	alpha, err := store.GetBlogCache(ctx, "Alpha")
+	if err != nil || alpha == nil {
+		t.Fatalf("GetBlogCache(Alpha) = %+v, %v", alpha, err)
+	}
+	if alpha.BlogMetadata != metadata {
+		t.Errorf("metadata = %+v, want %+v", alpha.BlogMetadata, metadata)
+	}
+	if alpha.LastNewArticleAt == nil {
+		t.Fatal("expected the new article time to be set")
+	}
+	assertRecent(t, "LastNewArticleAt", *alpha.LastNewArticleAt)
+	if alpha.Lifecycle != blogs.Active || alpha.LatestArticleHref != blogHref("Alpha")+"latest" {
+		t.Errorf("GetBlogCache(Alpha) = %+v", alpha)
+	}
+
+	beta, err := store.GetBlogCache(ctx, "Beta")
+	if err != nil || beta == nil || beta.Lifecycle != blogs.Archived {
+		t.Errorf("GetBlogCache(Beta) = %+v, %v, want an archived blog", beta, err)
+	}
+
+	// A new article replaces the cached one
+	err = store.UpsertBlogCache(ctx, blogs.BlogInfo{
+		BlogName: "Alpha", BlogHref: blogHref("Alpha"), Kind: blogs.Individual,
+		LatestArticleName: "Newer", LatestArticleHref: blogHref("Alpha") + "newer",
+	})
+	if err != nil {
+		t.Fatalf("UpsertBlogCache() error = %v", err)
+	}
+	if alpha, _ := store.GetBlogCache(ctx, "Alpha"); alpha == nil || alpha.LatestArticleName != "Newer" {
+		t.Errorf("GetBlogCache(Alpha) = %+v, want the newer article", alpha)
+	}
+}
+
+func testArticles(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+
+	articles := []blogs.Article{
+		{Name: "One", Href: "https://alpha.example/one"},
+		{Name: "Two", Href: "https://alpha.example/two"},
+	}
+	inserted, err := store.InsertArticles(ctx, "Alpha", articles)
+	if err != nil || inserted != 2 {
+		t.Fatalf("InsertArticles() = %d, %v, want 2", inserted, err)
+	}
+
+	inserted, err = store.InsertArticles(ctx, "Alpha", append(articles[1:], blogs.Article{Name: "Three", Href: "https://alpha.example/three"}))
+	if err != nil || inserted != 1 {
+		t.Errorf("InsertArticles() = %d, %v, want 1 for a single new article", inserted, err)
+	}
+}
+
+func testScrapeStatus(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+
+	if status, err := store.GetScrapeStatus(ctx, "Alpha"); status != nil || err != nil {
+		t.Fatalf("GetScrapeStatus() = %+v, %v, want nil, nil", status, err)
+	}
+
+	for range 2 {
+		if err := store.RecordScrapeFailure(ctx, "Alpha", "timeout"); err != nil {
+			t.Fatalf("RecordScrapeFailure() error = %v", err)
+		}
+	}
+	status, err := store.GetScrapeStatus(ctx, "Alpha")
+	if err != nil || status == nil {
+		t.Fatalf("GetScrapeStatus() = %+v, %v", status, err)
+	}
+	if status.ConsecutiveFailures != 2 || status.LastError != "timeout" || status.LastSuccessAt != nil {
+		t.Errorf("status after failures = %+v", status)
+	}
+	assertRecent(t, "LastAttemptAt", status.LastAttemptAt)
+
+	if err := store.RecordScrapeSuccess(ctx, "Alpha"); err != nil {
+		t.Fatalf("RecordScrapeSuccess() error = %v", err)
+	}
+	if err := store.RecordLayout(ctx, "Alpha", `{"tags":["main"]}`, 0.4, true); err != nil {
+		t.Fatalf("RecordLayout() error = %v", err)
+	}
+	chain := []blogs.Redirect{
+		{URL: "https://alpha.example/blog", StatusCode: 301},
+		{URL: "https://alpha.example/blog/", StatusCode: 308},
+	}
+	if err := store.RecordRedirects(ctx, "Alpha", "https://alpha.example/blog/", chain, true); err != nil {
+		t.Fatalf("RecordRedirects() error = %v", err)
+	}
+
+	status, err = store.GetScrapeStatus(ctx, "Alpha")
+	if err != nil || status == nil {
+		t.Fatalf("GetScrapeStatus() = %+v, %v", status, err)
+	}
+	if status.ConsecutiveFailures != 0 || status.LastError != "" || status.LastSuccessAt == nil {
+		t.Errorf("status after success = %+v", status)
+	}
+	if status.LayoutFingerprint != `{"tags":["main"]}` || status.LayoutSimilarity == nil || *status.LayoutSimilarity != 0.4 || status.LayoutChangedAt == nil {
+		t.Errorf("layout = %q, %v, %v", status.LayoutFingerprint, status.LayoutSimilarity, status.LayoutChangedAt)
+	}
+	if status.FinalURL != "https://alpha.example/blog/" || !slices.Equal(status.RedirectChain, chain) || !status.PermanentRedirect {
+		t.Errorf("redirects = %q, %+v, %v", status.FinalURL, status.RedirectChain, status.PermanentRedirect)
+	}
+
+	changes, err := store.GetLayoutChanges(ctx)
+	if err != nil || len(changes) != 1 || changes[0].BlogName != "Alpha" || changes[0].LayoutChangedAt == nil {
+		t.Fatalf("GetLayoutChanges() = %+v, %v, want Alpha", changes, err)
+	}
+	if cleared, err := store.ClearLayoutChange(ctx, "Alpha"); err != nil || !cleared {
+		t.Fatalf("ClearLayoutChange() = %v, %v, want true", cleared, err)
+	}
+	if cleared, err := store.ClearLayoutChange(ctx, "Alpha"); err != nil || cleared {
+		t.Errorf("ClearLayoutChange() = %v, %v, want false once cleared", cleared, err)
+	}
+	if changes, err := store.GetLayoutChanges(ctx); err != nil || len(changes) != 0 {
+		t.Errorf("GetLayoutChanges() = %+v, %v, want none", changes, err)
+	}
+	status, err = store.GetScrapeStatus(ctx, "Alpha")
+	if err != nil || status == nil {
+		t.Fatalf("GetScrapeStatus() = %+v, %v", status, err)
+	}
+	if status.LayoutFingerprint != "" || status.LayoutSimilarity != nil || status.LayoutChangedAt != nil || status.FinalURL != "https://alpha.example/blog/" {
+		t.Errorf("status after clearing the layout change = %+v", status)
+	}
+}
+
+func testBlogHrefChanges(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+	addBlog(t, db, "Beta", blogs.Organization)
+	cacheBlog(t, store, "Alpha", blogs.Individual)
+
+	queued, err := store.QueueBlogHrefChange(ctx, "Alpha", blogHref("Alpha"), "https://alpha.dev/")
+	if err != nil || !queued {
+		t.Fatalf("QueueBlogHrefChange() = %v, %v, want true", queued, err)
+	}
+	if queued, err := store.QueueBlogHrefChange(ctx, "Alpha", blogHref("Alpha"), "https://alpha.dev/"); err != nil || queued {
+		t.Errorf("QueueBlogHrefChange() = %v, %v, want false for a known change", queued, err)
+	}
+
+	pending, err := store.GetBlogHrefChanges(ctx, blogs.HrefChangePending)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("GetBlogHrefChanges(pending) = %+v, %v, want 1 change", pending, err)
+	}
+
+	change, err := store.ResolveBlogHrefChange(ctx, pending[0].ID, true)
+	if err != nil || change == nil || change.Status != blogs.HrefChangeApplied || change.ResolvedAt == nil {
+		t.Fatalf("ResolveBlogHrefChange() = %+v, %v", change, err)
+	}
+	if config, _ := store.GetBlogConfig(ctx, "Alpha"); config == nil || config.BlogHref != "https://alpha.dev/" {
+		t.Errorf("config after approval = %+v, want the new href", config)
+	}
+	if blog, _ := store.GetBlogCache(ctx, "Alpha"); blog == nil || blog.BlogHref != "https://alpha.dev/" {
+		t.Errorf("cache after approval = %+v, want the new href", blog)
+	}
+	if change, err := store.ResolveBlogHrefChange(ctx, pending[0].ID, false); change != nil || err != nil {
+		t.Errorf("ResolveBlogHrefChange() = %+v, %v, want nil, nil for a resolved change", change, err)
+	}
+
+	if err := store.ApplyBlogHrefChange(ctx, "Beta", "http://beta.example/", "https://beta.example/"); err != nil {
+		t.Fatalf("ApplyBlogHrefChange() error = %v", err)
+	}
+	all, err := store.GetBlogHrefChanges(ctx, "")
+	if err != nil || len(all) != 2 {
+		t.Fatalf("GetBlogHrefChanges() = %+v, %v, want 2 changes", all, err)
+	}
+	for _, change := range all {
+		if change.Status != blogs.HrefChangeApplied {
+			t.Errorf("change %+v, want applied", change)
+		}
+	}
+	if pending, _ := store.GetBlogHrefChanges(ctx, blogs.HrefChangePending); len(pending) != 0 {
+		t.Errorf("GetBlogHrefChanges(pending) = %+v, want none", pending)
+	}
+}
+
+func testPageSnapshots(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+
+	start := time.Now().Add(-time.Hour)
+	for i := range 3 {
+		snapshot := blogs.PageSnapshot{
+			BlogName:       "Alpha",
+			PageURL:        blogHref("Alpha"),
+			FetchedAt:      start.Add(time.Duration(i) * time.Minute),
+			Content:        fmt.Appendf(nil, "<html>page %d</html>", i),
+			MatchedElement: fmt.Sprintf("<a>%d</a>", i),
+		}
+		if err := store.SavePageSnapshot(ctx, snapshot, 2); err != nil {
+			t.Fatalf("SavePageSnapshot() error = %v", err)
+		}
+	}
+
+	snapshots, err := store.GetPageSnapshots(ctx, "Alpha", 0)
+	if err != nil {
+		t.Fatalf("GetPageSnapshots() error = %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("GetPageSnapshots() returned %d snapshots, want the 2 kept", len(snapshots))
+	}
+	if !bytes.Equal(snapshots[0].Content, []byte("<html>page 2</html>")) || snapshots[0].MatchedElement != "<a>2</a>" {
+		t.Errorf("latest snapshot = %q, %q", snapshots[0].Content, snapshots[0].MatchedElement)
+	}
+	if !bytes.Equal(snapshots[1].Content, []byte("<html>page 1</html>")) {
+		t.Errorf("previous snapshot = %q", snapshots[1].Content)
+	}
+
+	if latest, err := store.GetPageSnapshots(ctx, "Alpha", 1); err != nil || len(latest) != 1 {
+		t.Errorf("GetPageSnapshots(limit 1) = %d snapshots, %v", len(latest), err)
+	}
+}
+
+func testShadowResults(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+
+	results := []blogs.ShadowResult{
+		{BlogName: "Alpha", CheckedAt: time.Now().Add(-2 * time.Hour), LiveHref: "https://alpha.example/a", ShadowHref: "https://alpha.example/a", Agreed: true},
+		{BlogName: "Alpha", CheckedAt: time.Now().Add(-time.Hour), LiveHref: "https://alpha.example/b", ShadowError: "no match"},
+		{BlogName: "Alpha", CheckedAt: time.Now().AddDate(0, 0, -30), Agreed: true},
+	}
+	for _, result := range results {
+		if err := store.InsertShadowResult(ctx, result); err != nil {
+			t.Fatalf("InsertShadowResult() error = %v", err)
+		}
+	}
+
+	stats, err := store.GetShadowStats(ctx, time.Now().AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("GetShadowStats() error = %v", err)
+	}
+	if len(stats) != 1 || stats[0].Runs != 2 || stats[0].Agreements != 1 {
+		t.Fatalf("GetShadowStats() = %+v, want 2 recent runs with 1 agreement", stats)
+	}
+	if stats[0].LastDisagreement == nil || stats[0].LastDisagreement.ShadowError != "no match" {
+		t.Errorf("LastDisagreement = %+v", stats[0].LastDisagreement)
+	}
+}
+
+func testLinkChecks(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+	cacheBlog(t, store, "Alpha", blogs.Individual)
+
+	checks := []blogs.LinkCheck{
+		{BlogName: "Alpha", Kind: blogs.LinkBlog, URL: blogHref("Alpha"), StatusCode: 404, Status: blogs.LinkNotFound},
+		{BlogName: "Alpha", Kind: blogs.LinkArticle, URL: blogHref("Alpha") + "latest", StatusCode: 200, Status: blogs.LinkOK},
+	}
+	for _, check := range checks {
+		if err := store.RecordLinkCheck(ctx, check); err != nil {
+			t.Fatalf("RecordLinkCheck() error = %v", err)
+		}
+	}
+
+	all, err := store.GetLinkChecks(ctx, false)
+	if err != nil || len(all) != 2 {
+		t.Fatalf("GetLinkChecks() = %+v, %v, want 2 checks", all, err)
+	}
+	broken, err := store.GetLinkChecks(ctx, true)
+	if err != nil || len(broken) != 1 || broken[0].Kind != blogs.LinkBlog || broken[0].StatusCode != 404 {
+		t.Fatalf("GetLinkChecks(broken) = %+v, %v, want the blog link", broken, err)
+	}
+	assertRecent(t, "CheckedAt", broken[0].CheckedAt)
+
+	blog, err := store.GetBlogCache(ctx, "Alpha")
+	if err != nil || blog == nil || !slices.Equal(blog.BrokenLinks, []blogs.LinkKind{blogs.LinkBlog}) {
+		t.Fatalf("GetBlogCache() = %+v, %v, want a broken blog link", blog, err)
+	}
+
+	// A later check replaces the previous one
+	checks[0].Status, checks[0].StatusCode = blogs.LinkOK, 200
+	if err := store.RecordLinkCheck(ctx, checks[0]); err != nil {
+		t.Fatalf("RecordLinkCheck() error = %v", err)
+	}
+	if broken, _ := store.GetLinkChecks(ctx, true); len(broken) != 0 {
+		t.Errorf("GetLinkChecks(broken) = %+v, want none", broken)
+	}
+}
+
+func testBlogIcons(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+	cacheBlog(t, store, "Alpha", blogs.Individual)
+
+	if icon, err := store.GetBlogIcon(ctx, "Alpha"); icon != nil || err != nil {
+		t.Fatalf("GetBlogIcon() = %+v, %v, want nil, nil", icon, err)
+	}
+
+	icon := blogs.BlogIcon{
+		BlogName:    "Alpha",
+		SourceURL:   "https://alpha.example/favicon.ico",
+		Content:     []byte("\x89PNG\r\n\x1a\n\x00\xff"),
+		ContentHash: "0123456789abcdef0123456789abcdef",
+	}
+	if err := store.SaveBlogIcon(ctx, icon); err != nil {
+		t.Fatalf("SaveBlogIcon() error = %v", err)
+	}
+
+	got, err := store.GetBlogIcon(ctx, "Alpha")
+	if err != nil || got == nil {
+		t.Fatalf("GetBlogIcon() = %+v, %v", got, err)
+	}
+	if !bytes.Equal(got.Content, icon.Content) || got.SourceURL != icon.SourceURL || got.ContentHash != icon.ContentHash {
+		t.Errorf("GetBlogIcon() = %+v, want %+v", got, icon)
+	}
+	assertRecent(t, "FetchedAt", got.FetchedAt)
+
+	blog, err := store.GetBlogCache(ctx, "Alpha")
+	if err != nil || blog == nil || blog.IconHref != "/api/blogs/Alpha/icon?v=0123456789abcdef" {
+		t.Errorf("GetBlogCache() = %+v, %v, want the icon path", blog, err)
+	}
+}
+
+func testGitHubProfiles(t *testing.T, db *sql.DB, store blogs.Store) {
+	ctx := context.Background()
+	addBlog(t, db, "Alpha", blogs.Individual)
+	cacheBlog(t, store, "Alpha", blogs.Individual)
+
+	profile := blogs.GitHubProfile{
+		Login:     "alpha",
+		Name:      "Alpha A.",
+		Bio:       "Writes",
+		Followers: 12,
+		TopRepos:  []blogs.GitHubRepo{{Name: "tool", Href: "https://github.com/alpha/tool", Stars: 30}},
+	}
+	if err := store.SaveGitHubProfile(ctx, "Alpha", profile); err != nil {
+		t.Fatalf("SaveGitHubProfile() error = %v", err)
+	}
+
+	profiles, err := store.GetGitHubProfiles(ctx)
+	if err != nil {
+		t.Fatalf("GetGitHubProfiles() error = %v", err)
+	}
+	got, ok := profiles["Alpha"]
+	if !ok || got.Login != "alpha" || got.Followers != 12 || !slices.Equal(got.TopRepos, profile.TopRepos) {
+		t.Fatalf("GetGitHubProfiles() = %+v", profiles)
+	}
+
+	all, err := store.GetAllBlogs(ctx)
+	if err != nil || len(all) != 1 || all[0].GitHub == nil || all[0].GitHub.Name != "Alpha A." {
+		t.Errorf("GetAllBlogs() = %+v, %v, want the GitHub profile attached", all, err)
+	}
+}
@@ -14,3 +14,28 @@ var BlogListTemplate = template.Must(template.New("BlogList").Parse(blogListTemp
 var blogFeedTemplateContent string
 
 var BlogFeedTemplate = template.Must(template.New("BlogFeed").Parse(blogFeedTemplateContent))
+
+// BlogFeed is the data of BlogFeedTemplate: the blogs of the feed, and the tag
+// they were filtered by, if any.
+type BlogFeed struct {
+	Tag   *Tag
+	Blogs []BlogInfo
+}
+
+//go:embed templates/search_results.html.tmpl
+var searchResultsTemplateContent string
+
+var SearchResultsTemplate = template.Must(template.New("SearchResults").Parse(searchResultsTemplateContent))
+
+// SearchResults is the data of SearchResultsTemplate: the results of a
+// search, which renders nothing when Query is empty.
+type SearchResults struct {
+	Query   string
+	Results []SearchResult
+}
+
+//go:embed templates/blog_name_options.html.tmpl
+var blogNameOptionsTemplateContent string
+
+// BlogNameOptionsTemplate renders blog names as the options of a datalist.
+var BlogNameOptionsTemplate = template.Must(template.New("BlogNameOptions").Parse(blogNameOptionsTemplateContent))
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOpenSQLite_Pragmas(t *testing.T) {
+	db := openTestDB(t)
+
+	pragmas := []struct {
+		name string
+		want string
+	}{
+		{"journal_mode", "wal"},
+		{"busy_timeout", "5000"},
+		{"foreign_keys", "1"},
+		// NORMAL
+		{"synchronous", "1"},
+	}
+	// Check several connections, since pragmas are per connection
+	for range 3 {
+		conn, err := db.Conn(t.Context())
+		if err != nil {
+			t.Fatalf("failed to get connection: %v", err)
+		}
+		defer conn.Close()
+
+		for _, pragma := range pragmas {
+			var got string
+			if err := conn.QueryRowContext(t.Context(), "PRAGMA "+pragma.name).Scan(&got); err != nil {
+				t.Fatalf("PRAGMA %s error = %v", pragma.name, err)
+			}
+			if got != pragma.want {
+				t.Errorf("PRAGMA %s = %s, want %s", pragma.name, got, pragma.want)
+			}
+		}
+	}
+}
+
+func TestOpenSQLite_ForeignKeys(t *testing.T) {
+	db := openTestDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE parents (name TEXT PRIMARY KEY);
+		CREATE TABLE children (
+		    name TEXT NOT NULL,
+		    FOREIGN KEY (name) REFERENCES parents (name) ON DELETE CASCADE
+		);
+		INSERT INTO parents VALUES ('a');
+		INSERT INTO children VALUES ('a');
+	`)
+	if err != nil {
+		t.Fatalf("failed to create tables: %v", err)
+	}
+
+	if _, err := db.Exec("INSERT INTO children VALUES ('missing')"); err == nil {
+		t.Error("expected an orphan row to be refused")
+	}
+	if _, err := db.Exec("DELETE FROM parents"); err != nil {
+		t.Fatalf("failed to delete parent: %v", err)
+	}
+	var children int
+	db.QueryRow("SELECT COUNT(*) FROM children").Scan(&children)
+	if children != 0 {
+		t.Errorf("%d children left, want the delete to cascade", children)
+	}
+}
+
+func TestOpenSQLite_ReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "techblogs.db")
+	writer, err := InitDB(path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer writer.Close()
+	if _, err := writer.Exec("CREATE TABLE a (id INTEGER)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	options := DefaultSQLiteOptions()
+	options.ReadOnly = true
+	reader, err := OpenSQLite(path, options)
+	if err != nil {
+		t.Fatalf("failed to open database read-only: %v", err)
+	}
+	defer reader.Close()
+
+	if _, err := reader.Exec("INSERT INTO a VALUES (1)"); err == nil {
+		t.Error("expected a read-only database to refuse writes")
+	}
+	if _, err := OpenSQLite(filepath.Join(t.TempDir(), "missing.db"), options); err == nil {
+		t.Error("expected a missing database not to be created read-only")
+	}
+}
+
+// TestOpenSQLite_Concurrent writes from two pools while a read-only pool
+// reads, as the scraper and the API do from two processes, and expects no
+// "database is locked" error.
+func TestOpenSQLite_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "techblogs.db")
+	open := func(readOnly bool) *sql.DB {
+		options := DefaultSQLiteOptions()
+		options.ReadOnly = readOnly
+		db, err := OpenSQLite(path, options)
+		if err != nil {
+			t.Fatalf("failed to open database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	scraper := open(false)
+	if _, err := scraper.Exec("CREATE TABLE articles (id INTEGER PRIMARY KEY, writer TEXT NOT NULL)"); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	backfill := open(false)
+	api := open(true)
+
+	const writes = 50
+	errs := make(chan error, 2*writes)
+	var writers sync.WaitGroup
+	for _, db := range []*sql.DB{scraper, backfill} {
+		writers.Go(func() {
+			for i := range writes {
+				// Read then write in one transaction, holding the lock a while
+				tx, err := db.Begin()
+				if err != nil {
+					errs <- fmt.Errorf("begin %d: %w", i, err)
+					return
+				}
+				var count int
+				if err := tx.QueryRow("SELECT COUNT(*) FROM articles").Scan(&count); err != nil {
+					tx.Rollback()
+					errs <- fmt.Errorf("count %d: %w", i, err)
+					return
+				}
+				time.Sleep(time.Millisecond)
+				if _, err := tx.Exec("INSERT INTO articles (writer) VALUES (?)", fmt.Sprint(count)); err != nil {
+					tx.Rollback()
+					errs <- fmt.Errorf("insert %d: %w", i, err)
+					return
+				}
+				if err := tx.Commit(); err != nil {
+					errs <- fmt.Errorf("commit %d: %w", i, err)
+					return
+				}
+			}
+		})
+	}
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Go(func() {
+			last := 0
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				var count int
+				if err := api.QueryRow("SELECT COUNT(*) FROM articles").Scan(&count); err != nil {
+					errs <- fmt.Errorf("read: %w", err)
+					return
+				}
+				if count < last {
+					errs <- fmt.Errorf("read %d articles after %d", count, last)
+					return
+				}
+				last = count
+			}
+		})
+	}
+
+	writers.Wait()
+	close(done)
+	readers.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	var count int
+	api.QueryRow("SELECT COUNT(*) FROM articles").Scan(&count)
+	if count != 2*writes {
+		t.Errorf("read %d articles, want %d", count, 2*writes)
+	}
+}
+
+func TestSQLiteOptionsFromEnv(t *testing.T) {
+	t.Setenv("SQLITE_JOURNAL_MODE", "delete")
+	t.Setenv("SQLITE_BUSY_TIMEOUT", "250ms")
+	t.Setenv("SQLITE_FOREIGN_KEYS", "false")
+	t.Setenv("SQLITE_MAX_OPEN_CONNS", "1")
+
+	options, err := SQLiteOptionsFromEnv()
+	if err != nil {
+		t.Fatalf("SQLiteOptionsFromEnv() error = %v", err)
+	}
+	want := SQLiteOptions{JournalMode: "delete", BusyTimeout: 250 * time.Millisecond, Synchronous: "NORMAL", MaxOpenConns: 1}
+	if options != want {
+		t.Errorf("SQLiteOptionsFromEnv() = %+v, want %+v", options, want)
+	}
+
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "techblogs.db"), options)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	var journalMode string
+	db.QueryRow("PRAGMA journal_mode").Scan(&journalMode)
+	if journalMode != "delete" {
+		t.Errorf("PRAGMA journal_mode = %s, want delete", journalMode)
+	}
+
+	t.Setenv("SQLITE_MAX_OPEN_CONNS", "0")
+	if _, err := SQLiteOptionsFromEnv(); err == nil {
+		t.Error("expected an error for 0 connections")
+	}
+}
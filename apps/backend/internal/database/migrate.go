@@ -0,0 +1,246 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// migrationFile matches golang-migrate file names: 001_init.up.sql.
+var migrationFile = regexp.MustCompile(`^([0-9]+)_(.*)\.(up|down)\.sql$`)
+
+// Migration is a schema change, applied by its up script and reverted by its
+// down script.
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// LoadMigrations reads the migrations at the root of fsys, sorted by version.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return int(a.Version) - int(b.Version)
+	})
+	return migrations, nil
+}
+
+// Migrator applies migrations, tracking the schema version in the
+// schema_migrations table the way golang-migrate does, so that databases
+// migrated by either stay compatible. Each migration runs in a transaction
+// along with its version update.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator loads the migrations at the root of fsys to apply them to db.
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) == 0 {
+		return nil, errors.New("no migrations found")
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Migrations returns the known migrations, sorted by version.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Latest returns the version the migrations bring the schema to.
+func (m *Migrator) Latest() uint {
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the schema version, 0 when no migration was applied. A dirty
+// schema is one a golang-migrate migration failed on halfway, which needs
+// fixing by hand.
+func (m *Migrator) Version(ctx context.Context) (version uint, dirty bool, err error) {
+	const query = "SELECT version, dirty FROM schema_migrations LIMIT 1"
+	err = m.db.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	if err != nil && err != sql.ErrNoRows {
+		// The table is only created when missing, so that read-only
+		// databases can be checked
+		if err := m.ensureTable(ctx); err != nil {
+			return 0, false, err
+		}
+		err = m.db.QueryRowContext(ctx, query).Scan(&version, &dirty)
+	}
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// CheckVersion returns an error when the schema is dirty or older than the
+// latest migration. A newer schema is accepted, so that a release can be
+// rolled back without reverting its migrations.
+func (m *Migrator) CheckVersion(ctx context.Context) error {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("schema version %d is dirty", version)
+	}
+	if version < m.Latest() {
+		return fmt.Errorf("schema version %d is behind the expected version %d", version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies the pending migrations in order and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	version, err := m.cleanVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range m.migrations {
+		if migration.Version <= version {
+			continue
+		}
+		if err := m.apply(ctx, migration.Up, migration.Version); err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Down reverts up to steps migrations, latest first, and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	version, err := m.cleanVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for len(reverted) < steps && version > 0 {
+		i := slices.IndexFunc(m.migrations, func(migration Migration) bool {
+			return migration.Version == version
+		})
+		if i < 0 {
+			return reverted, fmt.Errorf("unknown schema version %d", version)
+		}
+		migration := m.migrations[i]
+		if migration.Down == "" {
+			return reverted, fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+
+		previous := uint(0)
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		if err := m.apply(ctx, migration.Down, previous); err != nil {
+			return reverted, fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		reverted = append(reverted, migration)
+		version = previous
+	}
+	return reverted, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// cleanVersion returns the schema version, refusing dirty schemas.
+func (m *Migrator) cleanVersion(ctx context.Context) (uint, error) {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("schema version %d is dirty: fix the schema, then set dirty to false in schema_migrations", version)
+	}
+	return version, nil
+}
+
+// apply runs a migration script and records the resulting version, 0
+// meaning that no migration is left applied.
+func (m *Migrator) apply(ctx context.Context, script string, version uint) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return fmt.Errorf("failed to clear schema version: %w", err)
+	}
+	if version > 0 {
+		// Formatted rather than bound, since placeholders differ between drivers
+		insert := fmt.Sprintf("INSERT INTO schema_migrations (version, dirty) VALUES (%d, FALSE)", version)
+		if _, err := tx.ExecContext(ctx, insert); err != nil {
+			return fmt.Errorf("failed to record schema version: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
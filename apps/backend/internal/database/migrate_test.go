@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nesco/techblogs/backend/migrations"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := InitDB(filepath.Join(t.TempDir(), "techblogs.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+var testMigrations = fstest.MapFS{
+	"001_init.up.sql":          {Data: []byte("CREATE TABLE a (id INTEGER);")},
+	"001_init.down.sql":        {Data: []byte("DROP TABLE a;")},
+	"002_add_b.up.sql":         {Data: []byte("CREATE TABLE b (id INTEGER);")},
+	"002_add_b.down.sql":       {Data: []byte("DROP TABLE b;")},
+	"004_add_c.up.sql":         {Data: []byte("CREATE TABLE c (id INTEGER); INSERT INTO c VALUES (1);")},
+	"004_add_c.down.sql":       {Data: []byte("DROP TABLE c;")},
+	"README.md":                {Data: []byte("not a migration")},
+	"postgres/001_init.up.sql": {Data: []byte("ignored")},
+}
+
+func TestLoadMigrations(t *testing.T) {
+	loaded, err := LoadMigrations(testMigrations)
+	if err != nil {
+		t.Fatalf("LoadMigrations() error = %v", err)
+	}
+
+	var names []string
+	for _, migration := range loaded {
+		names = append(names, migration.Name)
+		if migration.Up == "" || migration.Down == "" {
+			t.Errorf("migration %d is missing a script", migration.Version)
+		}
+	}
+	if got := strings.Join(names, ","); got != "init,add_b,add_c" {
+		t.Errorf("LoadMigrations() = %s, want init,add_b,add_c", got)
+	}
+
+	_, err = LoadMigrations(fstest.MapFS{"001_init.down.sql": {Data: []byte("DROP TABLE a;")}})
+	if err == nil {
+		t.Error("expected an error for a migration without up script")
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	migrator, err := NewMigrator(db, testMigrations)
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+
+	if err := migrator.CheckVersion(ctx); err == nil {
+		t.Error("expected an empty schema to be behind")
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil || len(applied) != 3 {
+		t.Fatalf("Up() = %d migrations, %v, want 3", len(applied), err)
+	}
+	if version, dirty, err := migrator.Version(ctx); version != 4 || dirty || err != nil {
+		t.Errorf("Version() = %d, %v, %v, want 4", version, dirty, err)
+	}
+	if err := migrator.CheckVersion(ctx); err != nil {
+		t.Errorf("CheckVersion() error = %v", err)
+	}
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("Up() = %d migrations, %v, want none", len(applied), err)
+	}
+
+	reverted, err := migrator.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 4 {
+		t.Fatalf("Down(1) = %+v, %v, want migration 4", reverted, err)
+	}
+	if version, _, _ := migrator.Version(ctx); version != 2 {
+		t.Errorf("Version() = %d after Down, want 2", version)
+	}
+	if err := migrator.CheckVersion(ctx); err == nil {
+		t.Error("expected version 2 to be behind")
+	}
+
+	reverted, err = migrator.Down(ctx, 5)
+	if err != nil || len(reverted) != 2 {
+		t.Fatalf("Down(5) = %d migrations, %v, want 2", len(reverted), err)
+	}
+	if version, _, _ := migrator.Version(ctx); version != 0 {
+		t.Errorf("Version() = %d after reverting everything, want 0", version)
+	}
+	var tables int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('a', 'b', 'c')").Scan(&tables)
+	if tables != 0 {
+		t.Errorf("%d tables left after reverting everything", tables)
+	}
+}
+
+func TestMigrator_FailedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	broken := fstest.MapFS{
+		"001_init.up.sql":   testMigrations["001_init.up.sql"],
+		"002_broken.up.sql": {Data: []byte("CREATE TABLE b (id INTEGER); SELECT * FROM missing;")},
+	}
+	migrator, err := NewMigrator(db, broken)
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err == nil || len(applied) != 1 {
+		t.Fatalf("Up() = %d migrations, %v, want 1 and an error", len(applied), err)
+	}
+	if version, dirty, _ := migrator.Version(ctx); version != 1 || dirty {
+		t.Errorf("Version() = %d, %v, want a clean version 1", version, dirty)
+	}
+	var tables int
+	db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'b'").Scan(&tables)
+	if tables != 0 {
+		t.Error("expected the failed migration to be rolled back")
+	}
+}
+
+// TestMigrator_GolangMigrate checks that databases migrated by the
+// golang-migrate CLI are picked up where it left them.
+func TestMigrator_GolangMigrate(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	_, err := db.Exec(`
+		CREATE TABLE schema_migrations (version uint64, dirty bool);
+		CREATE UNIQUE INDEX version_unique ON schema_migrations (version);
+		CREATE TABLE a (id INTEGER);
+		INSERT INTO schema_migrations (version, dirty) VALUES (1, 0);
+	`)
+	if err != nil {
+		t.Fatalf("failed to set up golang-migrate schema: %v", err)
+	}
+
+	migrator, err := NewMigrator(db, testMigrations)
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil || len(applied) != 2 || applied[0].Version != 2 {
+		t.Fatalf("Up() = %+v, %v, want migrations 2 and 4", applied, err)
+	}
+
+	if _, err := db.Exec("UPDATE schema_migrations SET dirty = 1"); err != nil {
+		t.Fatalf("failed to mark schema dirty: %v", err)
+	}
+	if err := migrator.CheckVersion(ctx); err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Errorf("CheckVersion() error = %v, want a dirty schema", err)
+	}
+	if _, err := migrator.Down(ctx, 1); err == nil {
+		t.Error("expected Down to refuse a dirty schema")
+	}
+}
+
+// TestEmbeddedMigrations applies and reverts every SQLite migration.
+func TestEmbeddedMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	migrator, err := NewMigrator(db, migrations.SQLite)
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if _, err := migrator.Down(ctx, len(migrator.Migrations())); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() after Down() error = %v", err)
+	}
+
+	if _, err := LoadMigrations(migrations.Postgres); err != nil {
+		t.Errorf("LoadMigrations(Postgres) error = %v", err)
+	}
+}
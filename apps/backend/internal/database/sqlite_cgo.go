@@ -0,0 +1,40 @@
+//go:build mattn
+
+package database
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteDriver is the database/sql driver SQLite databases are opened with:
+// mattn/go-sqlite3 when built with the mattn tag, which needs cgo. The search
+// index also needs its sqlite_fts5 tag, without which FTS5 is left out.
+const SQLiteDriver = "sqlite3"
+
+// DSN returns the data source name of the database at dbPath, which applies
+// the options to every connection of the pool.
+func (o SQLiteOptions) DSN(dbPath string) string {
+	params := url.Values{}
+	params.Set("_busy_timeout", strconv.FormatInt(o.BusyTimeout.Milliseconds(), 10))
+	params.Set("_foreign_keys", strconv.FormatBool(o.ForeignKeys))
+	if o.Synchronous != "" {
+		params.Set("_synchronous", strings.ToUpper(o.Synchronous))
+	}
+	if o.ReadOnly {
+		// The journal mode is persistent, and set by the writers
+		params.Set("mode", "ro")
+	} else {
+		if o.JournalMode != "" {
+			params.Set("_journal_mode", strings.ToUpper(o.JournalMode))
+		}
+		// Take the write lock when a transaction starts: a read transaction
+		// upgraded to a write fails at once instead of waiting when another
+		// connection wrote in between.
+		params.Set("_txlock", "immediate")
+	}
+	return "file:" + dbPath + "?" + params.Encode()
+}
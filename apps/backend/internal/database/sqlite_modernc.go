@@ -0,0 +1,44 @@
+//go:build !mattn
+
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteDriver is the database/sql driver SQLite databases are opened with:
+// modernc.org/sqlite by default, a translation of SQLite to Go that builds
+// without cgo and includes FTS5.
+const SQLiteDriver = "sqlite"
+
+// DSN returns the data source name of the database at dbPath, which applies
+// the options to every connection of the pool.
+func (o SQLiteOptions) DSN(dbPath string) string {
+	params := url.Values{}
+	pragma := func(name string, value any) {
+		params.Add("_pragma", fmt.Sprintf("%s(%v)", name, value))
+	}
+	pragma("busy_timeout", o.BusyTimeout.Milliseconds())
+	pragma("foreign_keys", o.ForeignKeys)
+	if o.Synchronous != "" {
+		pragma("synchronous", strings.ToUpper(o.Synchronous))
+	}
+	if o.ReadOnly {
+		// The journal mode is persistent, and set by the writers
+		params.Set("mode", "ro")
+	} else {
+		if o.JournalMode != "" {
+			pragma("journal_mode", strings.ToUpper(o.JournalMode))
+		}
+		// Take the write lock when a transaction starts, as sqlite_cgo.go does
+		params.Set("_txlock", "immediate")
+	}
+	// Write times the way mattn/go-sqlite3 does, so that databases can be
+	// shared between builds
+	params.Set("_time_format", "sqlite")
+	return "file:" + dbPath + "?" + params.Encode()
+}
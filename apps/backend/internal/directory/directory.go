@@ -0,0 +1,168 @@
+// Package directory reads the blog directory file, which lists the tags, the
+// blogs and how to scrape them, and plans its synchronization into
+// blog_configs and tags.
+package directory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+	"go.yaml.in/yaml/v3"
+)
+
+// File is the content of the directory file.
+type File struct {
+	Tags  []Tag  `json:"tags,omitempty"`
+	Blogs []Blog `json:"blogs"`
+}
+
+// Tag is a tag of the directory file. Blogs may only use declared tags.
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Directory is the decoded directory file.
+type Directory struct {
+	Tags  []blogs.Tag
+	Blogs []blogs.BlogConfig
+}
+
+// Blog is a blog of the directory file. Its fields mirror blogs.BlogConfig
+// under shorter names.
+type Blog struct {
+	Name     string         `json:"name"`
+	Href     string         `json:"href"`
+	Kind     blogs.Kind     `json:"kind"`
+	GitHub   string         `json:"github,omitempty"`
+	Feed     string         `json:"feed,omitempty"`
+	Platform blogs.Platform `json:"platform,omitempty"`
+	// ArticleHref, ArticleName and NextPage are CSS selectors.
+	ArticleHref string `json:"articleHref,omitempty"`
+	ArticleName string `json:"articleName,omitempty"`
+	NextPage    string `json:"nextPage,omitempty"`
+	// PageURLTemplate builds listing page URLs, with {page} replaced by the
+	// page number.
+	PageURLTemplate string                    `json:"pageURLTemplate,omitempty"`
+	HrefRule        *blogs.ExtractionRule     `json:"hrefRule,omitempty"`
+	NameRule        *blogs.ExtractionRule     `json:"nameRule,omitempty"`
+	Rules           *blogs.ValidationRules    `json:"rules,omitempty"`
+	Script          string                    `json:"script,omitempty"`
+	Shadow          *blogs.ExtractionStrategy `json:"shadow,omitempty"`
+	Archived        bool                      `json:"archived,omitempty"`
+	Tags            []string                  `json:"tags,omitempty"`
+}
+
+// Load reads and validates the directory file at path.
+func Load(path string) (Directory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Directory{}, fmt.Errorf("failed to read directory file: %w", err)
+	}
+	d, err := Parse(data)
+	if err != nil {
+		return Directory{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Parse decodes a YAML directory file and lints it. The error lists every
+// problem found, as Problems.
+func Parse(data []byte) (Directory, error) {
+	d, err := Decode(data)
+	if err != nil {
+		return Directory{}, err
+	}
+	if problems := Lint(d); len(problems) > 0 {
+		return Directory{}, problems
+	}
+	return d, nil
+}
+
+// Decode decodes a YAML directory file without linting it. Unknown fields
+// are refused, so that a typo does not silently drop a setting.
+func Decode(data []byte) (Directory, error) {
+	// The YAML goes through JSON to reuse the json tags of the models
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return Directory{}, fmt.Errorf("invalid YAML: %w", err)
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return Directory{}, fmt.Errorf("invalid directory file: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	var file File
+	if err := decoder.Decode(&file); err != nil {
+		return Directory{}, fmt.Errorf("invalid directory file: %w", err)
+	}
+
+	d := Directory{
+		Tags:  make([]blogs.Tag, 0, len(file.Tags)),
+		Blogs: make([]blogs.BlogConfig, 0, len(file.Blogs)),
+	}
+	for _, tag := range file.Tags {
+		d.Tags = append(d.Tags, blogs.Tag{Name: tag.Name, Description: tag.Description})
+	}
+	for _, blog := range file.Blogs {
+		d.Blogs = append(d.Blogs, blog.Config())
+	}
+	return d, nil
+}
+
+// Config returns the blog configuration the entry stands for.
+func (b Blog) Config() blogs.BlogConfig {
+	config := blogs.BlogConfig{
+		BlogName:            b.Name,
+		BlogHref:            b.Href,
+		Kind:                b.Kind,
+		ArticleHrefSelector: b.ArticleHref,
+		ArticleNameSelector: b.ArticleName,
+		GitHubHref:          b.GitHub,
+		NextPageSelector:    b.NextPage,
+		PageURLTemplate:     b.PageURLTemplate,
+		HrefRule:            b.HrefRule,
+		NameRule:            b.NameRule,
+		Platform:            b.Platform,
+		FeedHref:            b.Feed,
+		Script:              b.Script,
+		Shadow:              b.Shadow,
+		Archived:            b.Archived,
+		Tags:                slices.Sorted(slices.Values(b.Tags)),
+	}
+	if b.Rules != nil {
+		config.Rules = *b.Rules
+	}
+	return config
+}
+
+// FromConfig returns the directory entry of a blog configuration.
+func FromConfig(config blogs.BlogConfig) Blog {
+	blog := Blog{
+		Name:            config.BlogName,
+		Href:            config.BlogHref,
+		Kind:            config.Kind,
+		GitHub:          config.GitHubHref,
+		Feed:            config.FeedHref,
+		Platform:        config.Platform,
+		ArticleHref:     config.ArticleHrefSelector,
+		ArticleName:     config.ArticleNameSelector,
+		NextPage:        config.NextPageSelector,
+		PageURLTemplate: config.PageURLTemplate,
+		HrefRule:        config.HrefRule,
+		NameRule:        config.NameRule,
+		Script:          config.Script,
+		Shadow:          config.Shadow,
+		Archived:        config.Archived,
+		Tags:            config.Tags,
+	}
+	if rules := config.Rules; len(rules.TitleReplacements) > 0 || rules.HrefPattern != "" || len(rules.TitleBlocklist) > 0 {
+		blog.Rules = &rules
+	}
+	return blog
+}
@@ -0,0 +1,198 @@
+package directory
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+	"github.com/nesco/techblogs/backend/internal/database"
+	"github.com/nesco/techblogs/backend/migrations"
+)
+
+func TestParse(t *testing.T) {
+	d, err := Parse([]byte(`
+tags:
+  - name: databases
+    description: Storage engines
+  - name: web
+blogs:
+  - name: Alpha
+    href: https://alpha.example/
+    kind: individual
+    articleHref: article a
+    articleName: article h2
+    tags: [web, databases]
+  - name: Beta
+    href: https://beta.example/blog/
+    kind: organization
+    github: https://github.com/beta
+    feed: https://beta.example/feed.xml
+    rules:
+      titleBlocklist: [Blog]
+    hrefRule:
+      engine: xpath
+      selector: //article//a
+      attribute: href
+    archived: true
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := []blogs.Tag{{Name: "databases", Description: "Storage engines"}, {Name: "web"}}; !slices.Equal(d.Tags, want) {
+		t.Errorf("Parse() tags = %+v, want %+v", d.Tags, want)
+	}
+	configs := d.Blogs
+	if len(configs) != 2 {
+		t.Fatalf("Parse() = %+v, want 2 blogs", configs)
+	}
+	if alpha := configs[0]; alpha.BlogName != "Alpha" || alpha.Kind != blogs.Individual || alpha.ArticleHrefSelector != "article a" || alpha.ArticleNameSelector != "article h2" {
+		t.Errorf("Alpha = %+v", alpha)
+	}
+	if alpha := configs[0]; !slices.Equal(alpha.Tags, []string{"databases", "web"}) {
+		t.Errorf("Alpha tags = %v, want them sorted", alpha.Tags)
+	}
+	beta := configs[1]
+	if beta.GitHubHref != "https://github.com/beta" || beta.FeedHref != "https://beta.example/feed.xml" || !beta.Archived {
+		t.Errorf("Beta = %+v", beta)
+	}
+	if !slices.Equal(beta.Rules.TitleBlocklist, []string{"Blog"}) {
+		t.Errorf("Beta rules = %+v", beta.Rules)
+	}
+	if beta.HrefRule == nil || beta.HrefRule.Engine != blogs.EngineXPath || beta.HrefRule.Selector != "//article//a" {
+		t.Errorf("Beta href rule = %+v", beta.HrefRule)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		blog string
+		want string
+	}{
+		{"missing name", "href: https://a.example/\n    kind: individual", "name: is missing on blog #1"},
+		{"relative href", "name: A\n    href: /blog\n    kind: individual", "A: href: \"/blog\" is not an absolute URL"},
+		{"unknown kind", "name: A\n    href: https://a.example/\n    kind: company", "kind"},
+		{"unknown platform", "name: A\n    href: https://a.example/\n    kind: individual\n    platform: blogger", "platform"},
+		{"invalid feed", "name: A\n    href: https://a.example/\n    kind: individual\n    feed: feed.xml", "feed"},
+		{"unknown field", "name: A\n    href: https://a.example/\n    kind: individual\n    articleHerf: a", "articleHerf"},
+		{"invalid selector", "name: A\n    href: https://a.example/\n    kind: individual\n    articleHref: 'main a['", "articleHref"},
+		{"invalid xpath", "name: A\n    href: https://a.example/\n    kind: individual\n    hrefRule: {engine: xpath, selector: '//a['}", "hrefRule.selector"},
+		{"unknown engine", "name: A\n    href: https://a.example/\n    kind: individual\n    hrefRule: {engine: regex, selector: a}", "hrefRule.engine"},
+		{"invalid regex", "name: A\n    href: https://a.example/\n    kind: individual\n    rules: {hrefPattern: '(posts'}", "rules.hrefPattern"},
+		{"invalid shadow", "name: A\n    href: https://a.example/\n    kind: individual\n    shadow: {articleNameSelector: 'h2 >'}", "shadow.articleNameSelector"},
+		{"undeclared tag", "name: A\n    href: https://a.example/\n    kind: individual\n    tags: [rust]", `A: tags: "rust" is not a declared tag`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte("blogs:\n  - " + tt.blog + "\n"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+
+	_, err := Parse([]byte(`
+blogs:
+  - {name: A, href: "https://a.example/", kind: individual}
+  - {name: A, href: "https://b.example/", kind: individual}
+`))
+	if err == nil || !strings.Contains(err.Error(), "A: name: is used by 2 blogs") {
+		t.Errorf("Parse() error = %v, want a duplicate name to be refused", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	currentTags := []blogs.Tag{{Name: "kept"}, {Name: "removed"}, {Name: "changed", Description: "Before"}}
+	desiredTags := []blogs.Tag{{Name: "kept"}, {Name: "changed", Description: "After"}, {Name: "added"}}
+	current := []blogs.BlogConfig{
+		{BlogName: "Kept", BlogHref: "https://kept.example/", Kind: blogs.Individual},
+		{BlogName: "Removed", BlogHref: "https://removed.example/", Kind: blogs.Individual},
+		{BlogName: "Changed", BlogHref: "https://changed.example/", Kind: blogs.Individual, ArticleHrefSelector: "a"},
+	}
+	desired := []blogs.BlogConfig{
+		{BlogName: "Kept", BlogHref: "https://kept.example/", Kind: blogs.Individual},
+		{BlogName: "Changed", BlogHref: "https://changed.example/blog/", Kind: blogs.Individual, FeedHref: "https://changed.example/feed.xml", Tags: []string{"added"}},
+		{BlogName: "Zeta", BlogHref: "https://zeta.example/", Kind: blogs.Organization},
+		{BlogName: "Added", BlogHref: "https://added.example/", Kind: blogs.Organization},
+	}
+
+	plan := Diff(Directory{currentTags, current}, Directory{desiredTags, desired})
+	var out bytes.Buffer
+	plan.Print(&out)
+	want := "+ tag added\n~ tag changed: description\n- tag removed\n+ Added\n+ Zeta\n~ Changed: articleHref, feed, href, tags\n- Removed\n"
+	if out.String() != want {
+		t.Errorf("Print() =\n%s\nwant\n%s", out.String(), want)
+	}
+	if upserts := plan.Upserts(); len(upserts) != 3 || upserts[2].FeedHref != "https://changed.example/feed.xml" {
+		t.Errorf("Upserts() = %+v", upserts)
+	}
+
+	if upserts := plan.TagUpserts(); len(upserts) != 2 || upserts[1].Description != "After" {
+		t.Errorf("TagUpserts() = %+v", upserts)
+	}
+
+	if plan := Diff(Directory{desiredTags, desired}, Directory{desiredTags, desired}); !plan.Empty() {
+		t.Errorf("Diff() of identical configs = %+v, want an empty plan", plan)
+	}
+}
+
+// TestDirectoryFile checks that the directory file matches the blogs the
+// migrations seed, so that syncing a fresh database only adds the tags, and
+// that syncing it leaves nothing to change.
+func TestDirectoryFile(t *testing.T) {
+	desired, err := Load(filepath.Join("..", "..", "blogs.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	db, err := database.InitDB(filepath.Join(t.TempDir(), "techblogs.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	migrator, err := database.NewMigrator(db, migrations.SQLite)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	repo := blogs.NewRepository(db)
+	current := func() Directory {
+		t.Helper()
+		tags, err := repo.GetTags(context.Background())
+		if err != nil {
+			t.Fatalf("GetTags() error = %v", err)
+		}
+		configs, err := repo.GetAllBlogConfigs(context.Background())
+		if err != nil {
+			t.Fatalf("GetAllBlogConfigs() error = %v", err)
+		}
+		return Directory{tags, configs}
+	}
+
+	plan := Diff(current(), desired)
+	for _, update := range plan.Updates {
+		if !slices.Equal(update.Fields, []string{"tags"}) {
+			t.Errorf("blogs.yaml differs from the seeded %s: %s", update.Config.BlogName, strings.Join(update.Fields, ", "))
+		}
+	}
+	if len(plan.Inserts) > 0 || len(plan.Deletes) > 0 {
+		var out bytes.Buffer
+		plan.Print(&out)
+		t.Errorf("blogs.yaml differs from the seeded blogs:\n%s", out.String())
+	}
+
+	if err := plan.Apply(context.Background(), repo); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if plan := Diff(current(), desired); !plan.Empty() {
+		var out bytes.Buffer
+		plan.Print(&out)
+		t.Errorf("blogs.yaml still differs after a sync:\n%s", out.String())
+	}
+}
@@ -0,0 +1,285 @@
+package directory
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/xpath"
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// Problem is something wrong with a blog configuration, or with a tag when
+// BlogName is empty and Field starts with tags.
+type Problem struct {
+	BlogName string
+	// Field is the directory file field at fault, if any.
+	Field   string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.BlogName == "" {
+		return fmt.Sprintf("%s: %s", p.Field, p.Message)
+	}
+	if p.Field == "" {
+		return fmt.Sprintf("%s: %s", p.BlogName, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.BlogName, p.Field, p.Message)
+}
+
+// Problems is the error of a set of invalid blog configurations.
+type Problems []Problem
+
+func (p Problems) Error() string {
+	lines := make([]string, len(p))
+	for i, problem := range p {
+		lines[i] = problem.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// For returns the problems of the named blog.
+func (p Problems) For(blogName string) Problems {
+	var problems Problems
+	for _, problem := range p {
+		if problem.BlogName == blogName {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+// tagName is the form of tag names, which appear in URLs.
+var tagName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Lint checks the tags and blog configurations of a directory, each on its
+// own and against each other, and returns every problem found, in order.
+// Tag names must be lower-case words joined by dashes, URLs must be absolute
+// https URLs, GitHub links must point at github.com, selectors, XPath
+// expressions and regular expressions must compile, names and hosts must be
+// unique, and blogs may only use declared tags.
+func Lint(d Directory) Problems {
+	problems := lintTags(d.Tags)
+	declared := make(map[string]bool, len(d.Tags))
+	for _, tag := range d.Tags {
+		declared[tag.Name] = true
+	}
+
+	configs := d.Blogs
+	names := make(map[string]int)
+	hosts := make(map[string][]string)
+	for i, config := range configs {
+		if config.BlogName == "" {
+			problems = append(problems, Problem{Field: "name", Message: fmt.Sprintf("is missing on blog #%d", i+1)})
+		}
+		problems = append(problems, lintBlog(FromConfig(config))...)
+		for _, tag := range config.Tags {
+			if !declared[tag] {
+				problems = append(problems, Problem{config.BlogName, "tags", fmt.Sprintf("%q is not a declared tag", tag)})
+			}
+		}
+		names[config.BlogName]++
+		if host := blogHost(config.BlogHref); host != "" {
+			hosts[host] = append(hosts[host], config.BlogName)
+		}
+	}
+
+	reported := make(map[string]bool)
+	for _, config := range configs {
+		if count := names[config.BlogName]; count > 1 && config.BlogName != "" && !reported[config.BlogName] {
+			reported[config.BlogName] = true
+			problems = append(problems, Problem{config.BlogName, "name", fmt.Sprintf("is used by %d blogs", count)})
+		}
+		host := blogHost(config.BlogHref)
+		if others := slices.DeleteFunc(slices.Clone(hosts[host]), func(name string) bool { return name == config.BlogName }); host != "" && len(others) > 0 {
+			problems = append(problems, Problem{config.BlogName, "href", fmt.Sprintf("host %s is also used by %s", host, strings.Join(others, ", "))})
+		}
+	}
+	return problems
+}
+
+// lintTags checks the declared tags.
+func lintTags(tags []blogs.Tag) Problems {
+	var problems Problems
+	seen := make(map[string]bool, len(tags))
+	for i, tag := range tags {
+		field := fmt.Sprintf("tags[%d].name", i)
+		switch {
+		case tag.Name == "":
+			problems = append(problems, Problem{Field: field, Message: "is missing"})
+		case !tagName.MatchString(tag.Name):
+			problems = append(problems, Problem{Field: field, Message: fmt.Sprintf("%q is not lower-case words joined by dashes", tag.Name)})
+		case seen[tag.Name]:
+			problems = append(problems, Problem{Field: field, Message: fmt.Sprintf("%q is declared twice", tag.Name)})
+		}
+		seen[tag.Name] = true
+	}
+	return problems
+}
+
+// blogHost returns the host of a blog URL, lower-cased and without www.,
+// or "" when the URL does not parse.
+func blogHost(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// lintBlog checks a directory entry on its own.
+func lintBlog(b Blog) Problems {
+	var problems Problems
+	problem := func(field, format string, args ...any) {
+		problems = append(problems, Problem{b.Name, field, fmt.Sprintf(format, args...)})
+	}
+	check := func(field, message string) {
+		if message != "" {
+			problem(field, "%s", message)
+		}
+	}
+
+	if strings.TrimSpace(b.Name) != b.Name {
+		problem("name", "%q has leading or trailing spaces", b.Name)
+	}
+	check("href", checkURL(b.Href))
+	for i, tag := range b.Tags {
+		if slices.Contains(b.Tags[:i], tag) {
+			problem("tags", "%q is listed twice", tag)
+		}
+	}
+	if b.Kind != blogs.Individual && b.Kind != blogs.Organization {
+		problem("kind", "%q is neither %s nor %s", b.Kind, blogs.Individual, blogs.Organization)
+	}
+	if b.Platform != "" && !slices.Contains(blogs.Platforms, b.Platform) {
+		problem("platform", "%q is not a known platform", b.Platform)
+	}
+	if b.GitHub != "" {
+		check("github", checkGitHubURL(b.GitHub))
+	}
+	if b.Feed != "" {
+		check("feed", checkURL(b.Feed))
+	}
+
+	check("articleHref", checkSelector(b.ArticleHref))
+	check("articleName", checkSelector(b.ArticleName))
+	check("nextPage", checkSelector(b.NextPage))
+	if b.PageURLTemplate != "" {
+		if !strings.Contains(b.PageURLTemplate, "{page}") {
+			problem("pageURLTemplate", "%q has no {page}", b.PageURLTemplate)
+		} else {
+			check("pageURLTemplate", checkURL(strings.ReplaceAll(b.PageURLTemplate, "{page}", "2")))
+		}
+	}
+	problems = append(problems, lintRule(b.Name, "hrefRule", b.HrefRule)...)
+	problems = append(problems, lintRule(b.Name, "nameRule", b.NameRule)...)
+	if b.Rules != nil {
+		for i, replacement := range b.Rules.TitleReplacements {
+			check(fmt.Sprintf("rules.titleReplacements[%d].pattern", i), checkRegex(replacement.Pattern))
+		}
+		check("rules.hrefPattern", checkRegex(b.Rules.HrefPattern))
+	}
+	if shadow := b.Shadow; shadow != nil {
+		if shadow.Platform != "" && !slices.Contains(blogs.Platforms, shadow.Platform) {
+			problem("shadow.platform", "%q is not a known platform", shadow.Platform)
+		}
+		if shadow.FeedHref != "" {
+			check("shadow.feed", checkURL(shadow.FeedHref))
+		}
+		check("shadow.articleHrefSelector", checkSelector(shadow.ArticleHrefSelector))
+		check("shadow.articleNameSelector", checkSelector(shadow.ArticleNameSelector))
+		problems = append(problems, lintRule(b.Name, "shadow.hrefRule", shadow.HrefRule)...)
+		problems = append(problems, lintRule(b.Name, "shadow.nameRule", shadow.NameRule)...)
+	}
+	return problems
+}
+
+func lintRule(blogName, field string, rule *blogs.ExtractionRule) Problems {
+	if rule == nil {
+		return nil
+	}
+	var problems Problems
+	problem := func(subfield, message string) {
+		if message != "" {
+			problems = append(problems, Problem{blogName, field + "." + subfield, message})
+		}
+	}
+
+	switch rule.Engine {
+	case "", blogs.EngineCSS:
+		if rule.Selector == "" {
+			problem("selector", "is missing")
+		} else {
+			problem("selector", checkSelector(rule.Selector))
+		}
+	case blogs.EngineXPath:
+		if _, err := xpath.Compile(rule.Selector); err != nil {
+			problem("selector", fmt.Sprintf("%q is not a valid XPath expression: %v", rule.Selector, err))
+		}
+	default:
+		problem("engine", fmt.Sprintf("%q is neither %s nor %s", rule.Engine, blogs.EngineCSS, blogs.EngineXPath))
+	}
+	if rule.Index < 0 {
+		problem("index", fmt.Sprintf("%d is negative", rule.Index))
+	}
+	problem("regex", checkRegex(rule.Regex))
+	return problems
+}
+
+// checkURL returns why a URL is not an absolute https URL, or "".
+func checkURL(value string) string {
+	u, err := url.Parse(value)
+	switch {
+	case value == "":
+		return "is missing"
+	case err != nil:
+		return fmt.Sprintf("%q does not parse: %v", value, err)
+	case u.Scheme != "https" && u.Scheme != "http", u.Host == "":
+		return fmt.Sprintf("%q is not an absolute URL", value)
+	case u.Scheme != "https":
+		return fmt.Sprintf("%q does not use https", value)
+	}
+	return ""
+}
+
+// checkGitHubURL returns why a URL is not the https URL of a GitHub user or
+// organization, or "".
+func checkGitHubURL(value string) string {
+	if message := checkURL(value); message != "" {
+		return message
+	}
+	u, _ := url.Parse(value)
+	if host := strings.ToLower(u.Hostname()); host != "github.com" && host != "www.github.com" {
+		return fmt.Sprintf("%q does not point at github.com", value)
+	}
+	if strings.Trim(u.Path, "/") == "" {
+		return fmt.Sprintf("%q does not name a user or organization", value)
+	}
+	return ""
+}
+
+// checkSelector returns why a CSS selector does not compile as the scraper
+// compiles it, or "". An empty selector is unset.
+func checkSelector(selector string) string {
+	if selector == "" {
+		return ""
+	}
+	if _, err := cascadia.Compile(selector); err != nil {
+		return fmt.Sprintf("%q is not a valid CSS selector: %v", selector, err)
+	}
+	return ""
+}
+
+func checkRegex(pattern string) string {
+	if pattern == "" {
+		return ""
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return fmt.Sprintf("%q is not a valid regular expression: %v", pattern, err)
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package directory
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+func TestLint(t *testing.T) {
+	configs := []blogs.BlogConfig{
+		{BlogName: "Alpha", BlogHref: "https://alpha.example/", Kind: blogs.Individual, GitHubHref: "https://github.com/alpha", ArticleHrefSelector: "article a"},
+		{BlogName: "Beta", BlogHref: "http://beta.example/", Kind: blogs.Individual, GitHubHref: "https://gitlab.com/beta"},
+		{BlogName: "Gamma", BlogHref: "https://www.Alpha.example/gamma/", Kind: "company", ArticleHrefSelector: "article a["},
+		{BlogName: "Beta", BlogHref: "https://beta2.example/", Kind: blogs.Organization, PageURLTemplate: "https://beta2.example/page/"},
+		{BlogName: "Delta", BlogHref: "https://delta.example/", Kind: blogs.Organization, GitHubHref: "https://github.com/",
+			HrefRule: &blogs.ExtractionRule{Engine: blogs.EngineXPath, Selector: "//a[", Regex: "(post"}},
+	}
+
+	var got []string
+	for _, problem := range Lint(Directory{Blogs: configs}) {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		`Beta: href: "http://beta.example/" does not use https`,
+		`Beta: github: "https://gitlab.com/beta" does not point at github.com`,
+		`Gamma: kind: "company" is neither individual nor organization`,
+		`Gamma: articleHref: "article a[" is not a valid CSS selector: expected identifier, found EOF instead`,
+		`Beta: pageURLTemplate: "https://beta2.example/page/" has no {page}`,
+		`Delta: github: "https://github.com/" does not name a user or organization`,
+		`Delta: hrefRule.selector: "//a[" is not a valid XPath expression: expression must evaluate to a node-set`,
+		"Delta: hrefRule.regex: \"(post\" is not a valid regular expression: error parsing regexp: missing closing ): `(post`",
+		`Alpha: href: host alpha.example is also used by Gamma`,
+		`Beta: name: is used by 2 blogs`,
+		`Gamma: href: host alpha.example is also used by Alpha`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Lint() =\n%q\nwant\n%q", got, want)
+	}
+
+	if problems := Lint(Directory{Blogs: configs}).For("Alpha"); len(problems) != 1 || problems[0].Field != "href" {
+		t.Errorf("Lint().For(Alpha) = %v, want the shared host only", problems)
+	}
+	if problems := Lint(Directory{Blogs: configs[:1]}); len(problems) != 0 {
+		t.Errorf("Lint() of a valid config = %v, want none", problems)
+	}
+}
+
+func TestLint_Tags(t *testing.T) {
+	d := Directory{
+		Tags: []blogs.Tag{{Name: "databases"}, {Name: "Web Dev"}, {Name: "databases"}, {}},
+		Blogs: []blogs.BlogConfig{
+			{BlogName: "Alpha", BlogHref: "https://alpha.example/", Kind: blogs.Individual, Tags: []string{"databases", "databases", "rust"}},
+		},
+	}
+
+	var got []string
+	for _, problem := range Lint(d) {
+		got = append(got, problem.String())
+	}
+	want := []string{
+		`tags[1].name: "Web Dev" is not lower-case words joined by dashes`,
+		`tags[2].name: "databases" is declared twice`,
+		`tags[3].name: is missing`,
+		`Alpha: tags: "databases" is listed twice`,
+		`Alpha: tags: "rust" is not a declared tag`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Lint() =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,185 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/nesco/techblogs/backend/internal/blogs"
+)
+
+// Plan lists the changes bringing blog_configs and tags in line with the
+// directory file.
+type Plan struct {
+	Inserts []blogs.BlogConfig
+	Updates []Update
+	// Deletes names the blogs missing from the directory file, whose cache,
+	// articles and history are deleted along with them.
+	Deletes []string
+
+	TagInserts []blogs.Tag
+	// TagUpdates are the tags whose description changed.
+	TagUpdates []blogs.Tag
+	// TagDeletes names the tags missing from the directory file, which are
+	// removed from their blogs.
+	TagDeletes []string
+}
+
+// Update is a blog whose configuration differs from the directory file.
+type Update struct {
+	Config blogs.BlogConfig
+	// Fields names the changed fields of the directory entry.
+	Fields []string
+}
+
+// Diff plans the changes turning the current tags and configurations into
+// the desired ones, each list sorted by name.
+func Diff(current, desired Directory) Plan {
+	plan := diffTags(current.Tags, desired.Tags)
+
+	existing := make(map[string]blogs.BlogConfig, len(current.Blogs))
+	for _, config := range current.Blogs {
+		existing[config.BlogName] = config
+	}
+
+	wanted := make(map[string]bool, len(desired.Blogs))
+	for _, config := range desired.Blogs {
+		wanted[config.BlogName] = true
+		previous, ok := existing[config.BlogName]
+		if !ok {
+			plan.Inserts = append(plan.Inserts, config)
+			continue
+		}
+		if fields := changedFields(FromConfig(previous), FromConfig(config)); len(fields) > 0 {
+			plan.Updates = append(plan.Updates, Update{Config: config, Fields: fields})
+		}
+	}
+	for _, config := range current.Blogs {
+		if !wanted[config.BlogName] {
+			plan.Deletes = append(plan.Deletes, config.BlogName)
+		}
+	}
+
+	slices.SortFunc(plan.Inserts, func(a, b blogs.BlogConfig) int { return strings.Compare(a.BlogName, b.BlogName) })
+	slices.SortFunc(plan.Updates, func(a, b Update) int { return strings.Compare(a.Config.BlogName, b.Config.BlogName) })
+	slices.Sort(plan.Deletes)
+	return plan
+}
+
+// diffTags plans the tag changes of a Diff.
+func diffTags(current, desired []blogs.Tag) Plan {
+	existing := make(map[string]blogs.Tag, len(current))
+	for _, tag := range current {
+		existing[tag.Name] = tag
+	}
+
+	var plan Plan
+	wanted := make(map[string]bool, len(desired))
+	for _, tag := range desired {
+		wanted[tag.Name] = true
+		previous, ok := existing[tag.Name]
+		switch {
+		case !ok:
+			plan.TagInserts = append(plan.TagInserts, tag)
+		case previous.Description != tag.Description:
+			plan.TagUpdates = append(plan.TagUpdates, tag)
+		}
+	}
+	for _, tag := range current {
+		if !wanted[tag.Name] {
+			plan.TagDeletes = append(plan.TagDeletes, tag.Name)
+		}
+	}
+
+	byName := func(a, b blogs.Tag) int { return strings.Compare(a.Name, b.Name) }
+	slices.SortFunc(plan.TagInserts, byName)
+	slices.SortFunc(plan.TagUpdates, byName)
+	slices.Sort(plan.TagDeletes)
+	return plan
+}
+
+// Empty reports whether the plan changes nothing.
+func (p Plan) Empty() bool {
+	return len(p.Inserts) == 0 && len(p.Updates) == 0 && len(p.Deletes) == 0 &&
+		len(p.TagInserts) == 0 && len(p.TagUpdates) == 0 && len(p.TagDeletes) == 0
+}
+
+// Upserts returns the configurations to insert or update.
+func (p Plan) Upserts() []blogs.BlogConfig {
+	upserts := slices.Clone(p.Inserts)
+	for _, update := range p.Updates {
+		upserts = append(upserts, update.Config)
+	}
+	return upserts
+}
+
+// TagUpserts returns the tags to insert or update.
+func (p Plan) TagUpserts() []blogs.Tag {
+	return slices.Concat(p.TagInserts, p.TagUpdates)
+}
+
+// Apply makes the changes of the plan in a single transaction.
+func (p Plan) Apply(ctx context.Context, store blogs.Store) error {
+	return store.SyncDirectory(ctx, p.Upserts(), p.Deletes, p.TagUpserts(), p.TagDeletes)
+}
+
+// Print writes the plan, one tag or blog per line: + for inserts, ~ for
+// updates followed by the changed fields, - for deletes.
+func (p Plan) Print(w io.Writer) {
+	for _, tag := range p.TagInserts {
+		fmt.Fprintf(w, "+ tag %s\n", tag.Name)
+	}
+	for _, tag := range p.TagUpdates {
+		fmt.Fprintf(w, "~ tag %s: description\n", tag.Name)
+	}
+	for _, name := range p.TagDeletes {
+		fmt.Fprintf(w, "- tag %s\n", name)
+	}
+	for _, config := range p.Inserts {
+		fmt.Fprintf(w, "+ %s\n", config.BlogName)
+	}
+	for _, update := range p.Updates {
+		fmt.Fprintf(w, "~ %s: %s\n", update.Config.BlogName, strings.Join(update.Fields, ", "))
+	}
+	for _, blogName := range p.Deletes {
+		fmt.Fprintf(w, "- %s\n", blogName)
+	}
+}
+
+// changedFields compares two entries field by field, as written in the
+// directory file.
+func changedFields(a, b Blog) []string {
+	fieldsA, fieldsB := fieldValues(a), fieldValues(b)
+	keys := slices.Sorted(maps.Keys(fieldsA))
+	for key := range fieldsB {
+		if _, ok := fieldsA[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	var changed []string
+	for _, key := range keys {
+		if fieldsA[key] != fieldsB[key] {
+			changed = append(changed, key)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// fieldValues returns the JSON encoding of each set field of an entry.
+func fieldValues(blog Blog) map[string]string {
+	encoded, _ := json.Marshal(blog)
+	var fields map[string]json.RawMessage
+	json.Unmarshal(encoded, &fields)
+
+	values := make(map[string]string, len(fields))
+	for key, value := range fields {
+		values[key] = string(value)
+	}
+	return values
+}
@@ -5,6 +5,8 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/nesco/techblogs/backend/internal/blogs"
 	"go.uber.org/zap"
@@ -12,34 +14,71 @@ import (
 
 type HomeHandler struct {
 	Logger zap.SugaredLogger
-	Repo   *blogs.Repository
+	Repo   blogs.Store
 }
 
 type HomePageData struct {
 	People        []blogs.BlogInfo
 	Organizations []blogs.BlogInfo
+	// Inactive lists the dead and archived blogs of both kinds, apart from
+	// the ones still publishing.
+	Inactive []blogs.BlogInfo
+	// Tags lists the tags of at least one blog, and Tag the one the page
+	// is filtered by with ?tag=, if any.
+	Tags []blogs.Tag
+	Tag  *blogs.Tag
 }
 
 func (a *HomeHandler) Read(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-	people, err := a.Repo.GetBlogsByKind(blogs.Individual)
+	people, err := a.Repo.GetBlogsByKind(r.Context(), blogs.Individual)
 	if err != nil {
 		a.Logger.Errorf("error fetching people blogs: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	organizations, err := a.Repo.GetBlogsByKind(blogs.Organization)
+	organizations, err := a.Repo.GetBlogsByKind(r.Context(), blogs.Organization)
 	if err != nil {
 		a.Logger.Errorf("error fetching organization blogs: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	tags, err := a.Repo.GetTags(r.Context())
+	if err != nil {
+		a.Logger.Errorf("error fetching tags: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	tags = slices.DeleteFunc(tags, func(tag blogs.Tag) bool { return tag.Blogs == 0 })
+
+	// An unknown tag is ignored rather than showing an empty page
+	var active *blogs.Tag
+	if name := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("tag"))); name != "" {
+		for i := range tags {
+			if tags[i].Name == name {
+				active = &tags[i]
+			}
+		}
+	}
+	if active != nil {
+		people = blogs.FilterByTag(people, active.Name)
+		organizations = blogs.FilterByTag(organizations, active.Name)
+	}
+
+	inactive := blogs.FilterByLifecycle(slices.Concat(people, organizations), blogs.Dead, blogs.Archived)
+	slices.SortFunc(inactive, func(a, b blogs.BlogInfo) int {
+		return strings.Compare(strings.ToLower(a.BlogName), strings.ToLower(b.BlogName))
+	})
+
 	data := HomePageData{
-		People:        people,
-		Organizations: organizations,
+		People:        blogs.FilterByLifecycle(people, blogs.ShownLifecycles...),
+		Organizations: blogs.FilterByLifecycle(organizations, blogs.ShownLifecycles...),
+		Inactive:      inactive,
+		Tags:          tags,
+		Tag:           active,
 	}
 
 	var buffer bytes.Buffer
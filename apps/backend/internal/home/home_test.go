@@ -1,45 +1,26 @@
 package home
 
 import (
+	"context"
 	"database/sql"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/nesco/techblogs/backend/internal/blogs"
+	"github.com/nesco/techblogs/backend/internal/database"
 	"go.uber.org/zap"
 )
 
 func TestGetHome(t *testing.T) {
-	// Create in-memory database for testing
-	db, err := sql.Open("sqlite3", ":memory:")
-	if err != nil {
-		t.Fatalf("failed to open test database: %v", err)
-	}
+	db := openTestDB(t)
 	defer db.Close()
 
-	// Create tables
-	_, err = db.Exec(`
-		CREATE TABLE blog_cache (
-			id INTEGER PRIMARY KEY,
-			blog_name TEXT NOT NULL UNIQUE,
-			blog_href TEXT NOT NULL,
-			latest_article_name TEXT,
-			latest_article_href TEXT,
-			kind TEXT NOT NULL CHECK (kind IN ('organization', 'individual')),
-			github_href TEXT NOT NULL DEFAULT '',
-			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-		)
-	`)
-	if err != nil {
-		t.Fatalf("failed to create table: %v", err)
-	}
-
 	// Insert test data
-	_, err = db.Exec(`
+	_, err := db.Exec(`
 		INSERT INTO blog_cache (blog_name, blog_href, latest_article_name, latest_article_href, kind)
 		VALUES ('Test Blog', 'https://example.com', 'Test Article', 'https://example.com/article', 'individual')
 	`)
@@ -81,3 +62,297 @@ func TestGetHome(t *testing.T) {
 		t.Errorf("expected body to start with %q, got %q", doctype, bodyStart)
 	}
 }
+
+// openTestDB returns an in-memory database holding the tables the home page
+// reads.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	// Create in-memory database for testing
+	db, err := sql.Open(database.SQLiteDriver, ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+
+	// Create tables
+	_, err = db.Exec(`
+		CREATE TABLE blog_cache (
+			id INTEGER PRIMARY KEY,
+			blog_name TEXT NOT NULL UNIQUE,
+			blog_href TEXT NOT NULL,
+			latest_article_name TEXT,
+			latest_article_href TEXT,
+			kind TEXT NOT NULL CHECK (kind IN ('organization', 'individual')),
+			github_href TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_new_article_at DATETIME,
+			description TEXT NOT NULL DEFAULT '',
+			site_name TEXT NOT NULL DEFAULT '',
+			lang TEXT NOT NULL DEFAULT '',
+			favicon_href TEXT NOT NULL DEFAULT '',
+			feed_href TEXT NOT NULL DEFAULT ''
+		);
+		CREATE TABLE blog_configs (
+			blog_name TEXT NOT NULL UNIQUE,
+			archived BOOLEAN NOT NULL DEFAULT 0,
+			disabled BOOLEAN NOT NULL DEFAULT 0
+		);
+		CREATE TABLE link_checks (
+			blog_name TEXT NOT NULL,
+			link_kind TEXT NOT NULL,
+			url TEXT NOT NULL,
+			checked_at DATETIME NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL,
+			detail TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (blog_name, link_kind)
+		);
+		CREATE TABLE blog_icons (
+			blog_name TEXT PRIMARY KEY,
+			source_url TEXT NOT NULL,
+			content BLOB NOT NULL,
+			content_hash TEXT NOT NULL,
+			fetched_at DATETIME NOT NULL
+		);
+		CREATE TABLE github_profiles (
+			blog_name TEXT PRIMARY KEY,
+			login TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			bio TEXT NOT NULL DEFAULT '',
+			followers INTEGER NOT NULL DEFAULT 0,
+			top_repos TEXT NOT NULL DEFAULT '[]',
+			fetched_at DATETIME NOT NULL
+		);
+		CREATE TABLE tags (
+			name TEXT PRIMARY KEY,
+			description TEXT NOT NULL DEFAULT ''
+		);
+		CREATE TABLE blog_tags (
+			blog_name TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (blog_name, tag)
+		)
+	`)
+	if err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+
+	return db
+}
+
+func TestGetHome_BrokenLinks(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO blog_cache (blog_name, blog_href, latest_article_name, latest_article_href, kind, github_href)
+		VALUES
+			('Broken Blog', 'https://broken.example.com', 'Gone Article', 'https://broken.example.com/gone', 'individual', 'https://github.com/deleted'),
+			('Fixed Blog', 'https://fixed.example.com', 'New Article', 'https://fixed.example.com/new', 'individual', 'https://github.com/fixed');
+		INSERT INTO link_checks (blog_name, link_kind, url, checked_at, status)
+		VALUES
+			('Broken Blog', 'article', 'https://broken.example.com/gone', CURRENT_TIMESTAMP, 'not_found'),
+			('Broken Blog', 'github', 'https://github.com/deleted', CURRENT_TIMESTAMP, 'not_found'),
+			('Fixed Blog', 'article', 'https://fixed.example.com/old', CURRENT_TIMESTAMP, 'soft_404'),
+			('Fixed Blog', 'github', 'https://github.com/fixed', CURRENT_TIMESTAMP, 'error')
+	`)
+	if err != nil {
+		t.Fatalf("failed to insert test data: %v", err)
+	}
+
+	logger := zap.NewNop().Sugar()
+	api := HomeHandler{Logger: *logger, Repo: blogs.NewRepository(db)}
+	rec := httptest.NewRecorder()
+	api.Read(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+
+	if strings.Contains(body, `href="https://broken.example.com/gone"`) || !strings.Contains(body, "Gone Article") {
+		t.Error("expected the broken article to be shown without its link")
+	}
+	if strings.Contains(body, "https://github.com/deleted") {
+		t.Error("expected the broken GitHub link to be hidden")
+	}
+	// Checks of a previous URL or temporary errors do not hide links
+	if !strings.Contains(body, `href="https://fixed.example.com/new"`) {
+		t.Error("expected the new article link to be shown")
+	}
+	if !strings.Contains(body, "https://github.com/fixed") {
+		t.Error("expected the temporarily failing GitHub link to be shown")
+	}
+}
+
+func TestGetHome_Lifecycle(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	_, err := db.Exec(`
+		INSERT INTO blog_cache (blog_name, blog_href, latest_article_name, latest_article_href, kind, last_new_article_at)
+		VALUES
+			('Active Blog', 'https://active.example.com', 'Fresh', 'https://active.example.com/fresh', 'individual', ?),
+			('Dormant Blog', 'https://dormant.example.com', 'Stale', 'https://dormant.example.com/stale', 'organization', ?),
+			('Dead Blog', 'https://dead.example.com', 'Ancient', 'https://dead.example.com/ancient', 'individual', ?),
+			('Archived Blog', 'https://archived.example.com', 'Last', 'https://archived.example.com/last', 'organization', ?);
+		INSERT INTO blog_configs (blog_name, archived) VALUES ('Archived Blog', 1)
+	`, now, now.AddDate(-1, 0, 0), now.AddDate(-3, 0, 0), now)
+	if err != nil {
+		t.Fatalf("failed to insert test data: %v", err)
+	}
+
+	logger := zap.NewNop().Sugar()
+	api := HomeHandler{Logger: *logger, Repo: blogs.NewRepository(db)}
+	rec := httptest.NewRecorder()
+	api.Read(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+
+	inactiveAt := strings.Index(body, "Inactive blogs")
+	if inactiveAt < 0 {
+		t.Fatal("expected an inactive blogs section")
+	}
+	for _, name := range []string{"Active Blog", "Dormant Blog"} {
+		if at := strings.Index(body, name); at < 0 || at > inactiveAt {
+			t.Errorf("expected %s among the publishing blogs", name)
+		}
+	}
+	for _, name := range []string{"Dead Blog", "Archived Blog"} {
+		if at := strings.Index(body, name); at < inactiveAt {
+			t.Errorf("expected %s in the inactive section only", name)
+		}
+	}
+	if strings.Contains(body, "dead.example.com/ancient") {
+		t.Error("expected no latest article for inactive blogs")
+	}
+}
+
+func TestGetHome_Metadata(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO blog_cache (blog_name, blog_href, latest_article_name, latest_article_href, kind, description, favicon_href)
+		VALUES ('Test Blog', 'https://example.com', 'Test Article', 'https://example.com/article', 'individual', 'Notes & essays', 'https://example.com/favicon.ico');
+		INSERT INTO blog_icons (blog_name, source_url, content, content_hash, fetched_at)
+		VALUES ('Test Blog', 'https://example.com/favicon.ico', x'89504e47', '0123456789abcdef0123456789abcdef', CURRENT_TIMESTAMP)
+	`)
+	if err != nil {
+		t.Fatalf("failed to insert test data: %v", err)
+	}
+
+	logger := zap.NewNop().Sugar()
+	api := HomeHandler{Logger: *logger, Repo: blogs.NewRepository(db)}
+	rec := httptest.NewRecorder()
+	api.Read(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `src="/api/blogs/Test%20Blog/icon?v=0123456789abcdef"`) {
+		t.Error("expected the locally served blog icon")
+	}
+	if strings.Contains(body, "https://example.com/favicon.ico") {
+		t.Error("expected the favicon not to be loaded from the blog")
+	}
+	if !strings.Contains(body, "Notes &amp; essays") {
+		t.Error("expected the escaped blog description")
+	}
+}
+
+func TestGetHome_GitHubProfile(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO blog_cache (blog_name, blog_href, latest_article_name, latest_article_href, kind, github_href)
+		VALUES ('Test Person', 'https://example.com', 'Test Article', 'https://example.com/article', 'individual', 'https://github.com/octocat');
+		INSERT INTO github_profiles (blog_name, login, name, bio, followers, top_repos, fetched_at)
+		VALUES ('Test Person', 'octocat', 'The Octocat', 'Builds <things>', 4200,
+			'[{"name":"hello-world","href":"https://github.com/octocat/hello-world","stars":1500}]', CURRENT_TIMESTAMP)
+	`)
+	if err != nil {
+		t.Fatalf("failed to insert test data: %v", err)
+	}
+
+	logger := zap.NewNop().Sugar()
+	api := HomeHandler{Logger: *logger, Repo: blogs.NewRepository(db)}
+	rec := httptest.NewRecorder()
+	api.Read(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	body := rec.Body.String()
+	for _, expected := range []string{
+		"Builds &lt;things&gt;",
+		"4200 GitHub followers",
+		`<a href="https://github.com/octocat/hello-world"`,
+		"★ 1500",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in the home page", expected)
+		}
+	}
+}
+
+func TestGetHome_Tags(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`
+		INSERT INTO blog_cache (blog_name, blog_href, latest_article_name, latest_article_href, kind)
+		VALUES
+			('Storage Person', 'https://storage.example.com', 'B-trees', 'https://storage.example.com/btrees', 'individual'),
+			('Crypto Company', 'https://crypto.example.com', 'Keys', 'https://crypto.example.com/keys', 'organization');
+		INSERT INTO tags (name, description) VALUES ('databases', 'Storage engines'), ('security', ''), ('unused', '');
+		INSERT INTO blog_tags (blog_name, tag) VALUES ('Storage Person', 'databases'), ('Crypto Company', 'security')
+	`)
+	if err != nil {
+		t.Fatalf("failed to insert test data: %v", err)
+	}
+
+	logger := zap.NewNop().Sugar()
+	api := HomeHandler{Logger: *logger, Repo: blogs.NewRepository(db)}
+	rec := httptest.NewRecorder()
+	api.Read(rec, httptest.NewRequest(http.MethodGet, "/?tag=databases", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status code 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, expected := range []string{
+		`<a href="/?tag=databases" title="Storage engines"`,
+		`<a href="/?tag=security"`,
+		"Storage Person",
+		`href="/api/tags/databases/rss.xml"`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in the home page", expected)
+		}
+	}
+	if strings.Contains(body, "Crypto Company") {
+		t.Error("expected the blogs without the tag to be filtered out")
+	}
+	if strings.Contains(body, "unused") {
+		t.Error("expected no chip for a tag without blogs")
+	}
+}
+
+func TestGetHome_CanceledRequest(t *testing.T) {
+	db := openTestDB(t)
+	defer db.Close()
+
+	logger := zap.NewNop().Sugar()
+	api := HomeHandler{Logger: *logger, Repo: blogs.NewRepository(db)}
+
+	// A client that went away must not keep the database busy
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec := httptest.NewRecorder()
+	api.Read(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d for a canceled request, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
@@ -0,0 +1,4 @@
+-- Remove article history and pagination settings
+ALTER TABLE blog_configs DROP COLUMN page_url_template;
+ALTER TABLE blog_configs DROP COLUMN next_page_selector;
+DROP TABLE IF EXISTS articles;
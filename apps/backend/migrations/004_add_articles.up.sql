@@ -0,0 +1,16 @@
+-- Keep a history of every article seen for a blog and let blogs describe
+-- how to reach older listing pages
+CREATE TABLE IF NOT EXISTS articles (
+    id INTEGER PRIMARY KEY,
+    blog_name TEXT NOT NULL,
+    article_name TEXT NOT NULL,
+    article_href TEXT NOT NULL,
+    discovered_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (blog_name, article_href),
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+ALTER TABLE blog_configs ADD COLUMN next_page_selector TEXT NOT NULL DEFAULT '';
+ALTER TABLE blog_configs ADD COLUMN page_url_template TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Remove validation rules and scrape status
+DROP TABLE IF EXISTS scrape_status;
+ALTER TABLE blog_configs DROP COLUMN validation_rules;
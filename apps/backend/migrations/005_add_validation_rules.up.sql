@@ -0,0 +1,14 @@
+-- Per-blog rules cleaning up and validating extracted articles, and the
+-- outcome of the latest scrape of each blog
+ALTER TABLE blog_configs ADD COLUMN validation_rules TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS scrape_status (
+    blog_name TEXT PRIMARY KEY,
+    last_attempt_at DATETIME NOT NULL,
+    last_success_at DATETIME,
+    last_error TEXT NOT NULL DEFAULT '',
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- Remove extraction rules
+ALTER TABLE blog_configs DROP COLUMN article_name_rule;
+ALTER TABLE blog_configs DROP COLUMN article_href_rule;
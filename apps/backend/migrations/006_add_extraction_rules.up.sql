@@ -0,0 +1,3 @@
+-- Optional JSON extraction rules replacing the plain CSS selectors
+ALTER TABLE blog_configs ADD COLUMN article_href_rule TEXT NOT NULL DEFAULT '';
+ALTER TABLE blog_configs ADD COLUMN article_name_rule TEXT NOT NULL DEFAULT '';
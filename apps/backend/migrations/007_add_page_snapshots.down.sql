@@ -0,0 +1,3 @@
+-- Remove page snapshots
+DROP INDEX IF EXISTS idx_page_snapshots_blog;
+DROP TABLE IF EXISTS page_snapshots;
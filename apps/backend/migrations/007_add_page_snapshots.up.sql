@@ -0,0 +1,15 @@
+-- Compressed copies of fetched listing pages, for offline re-extraction
+CREATE TABLE IF NOT EXISTS page_snapshots (
+    id INTEGER PRIMARY KEY,
+    blog_name TEXT NOT NULL,
+    page_url TEXT NOT NULL,
+    fetched_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    content BLOB NOT NULL,
+    matched_element TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_page_snapshots_blog
+ON page_snapshots (blog_name, fetched_at);
@@ -0,0 +1,4 @@
+-- Remove layout fingerprints
+ALTER TABLE scrape_status DROP COLUMN layout_changed_at;
+ALTER TABLE scrape_status DROP COLUMN layout_similarity;
+ALTER TABLE scrape_status DROP COLUMN layout_fingerprint;
@@ -0,0 +1,5 @@
+-- Fingerprint of the DOM structure around the matched article element, as
+-- of the latest successful scrape
+ALTER TABLE scrape_status ADD COLUMN layout_fingerprint TEXT NOT NULL DEFAULT '';
+ALTER TABLE scrape_status ADD COLUMN layout_similarity REAL;
+ALTER TABLE scrape_status ADD COLUMN layout_changed_at DATETIME;
@@ -0,0 +1,2 @@
+-- Remove blog platforms
+ALTER TABLE blog_configs DROP COLUMN platform;
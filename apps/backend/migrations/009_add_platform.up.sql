@@ -0,0 +1,3 @@
+-- Blog engine used to read the latest article ('' uses the selectors,
+-- 'auto' detects it)
+ALTER TABLE blog_configs ADD COLUMN platform TEXT NOT NULL DEFAULT '';
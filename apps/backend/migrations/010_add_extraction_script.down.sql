@@ -0,0 +1,2 @@
+-- Remove extraction scripts
+ALTER TABLE blog_configs DROP COLUMN extraction_script;
@@ -0,0 +1,2 @@
+-- Optional Starlark script extracting the latest article of odd sites
+ALTER TABLE blog_configs ADD COLUMN extraction_script TEXT NOT NULL DEFAULT '';
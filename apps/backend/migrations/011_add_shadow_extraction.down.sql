@@ -0,0 +1,4 @@
+-- Remove shadow extraction
+DROP INDEX IF EXISTS idx_shadow_results_checked_at;
+DROP TABLE IF EXISTS shadow_results;
+ALTER TABLE blog_configs DROP COLUMN shadow_config;
@@ -0,0 +1,22 @@
+-- Alternative extraction strategy run in shadow next to the live one, and
+-- the comparison of both on every scrape
+ALTER TABLE blog_configs ADD COLUMN shadow_config TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS shadow_results (
+    id INTEGER PRIMARY KEY,
+    blog_name TEXT NOT NULL,
+    checked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    live_name TEXT NOT NULL DEFAULT '',
+    live_href TEXT NOT NULL DEFAULT '',
+    live_error TEXT NOT NULL DEFAULT '',
+    shadow_name TEXT NOT NULL DEFAULT '',
+    shadow_href TEXT NOT NULL DEFAULT '',
+    shadow_error TEXT NOT NULL DEFAULT '',
+    agreed BOOLEAN NOT NULL DEFAULT 0,
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shadow_results_checked_at
+ON shadow_results (checked_at);
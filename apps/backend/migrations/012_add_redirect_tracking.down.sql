@@ -0,0 +1,5 @@
+-- Remove redirect tracking
+DROP TABLE IF EXISTS blog_href_changes;
+ALTER TABLE scrape_status DROP COLUMN permanent_redirect;
+ALTER TABLE scrape_status DROP COLUMN redirect_chain;
+ALTER TABLE scrape_status DROP COLUMN final_url;
@@ -0,0 +1,21 @@
+-- Redirects followed by the latest scrape of each blog, and the queue of
+-- blog URL changes they suggest
+ALTER TABLE scrape_status ADD COLUMN final_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE scrape_status ADD COLUMN redirect_chain TEXT NOT NULL DEFAULT '';
+ALTER TABLE scrape_status ADD COLUMN permanent_redirect BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS blog_href_changes (
+    id INTEGER PRIMARY KEY,
+    blog_name TEXT NOT NULL,
+    old_href TEXT NOT NULL,
+    new_href TEXT NOT NULL,
+    detected_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (
+        status IN ('pending', 'applied', 'rejected')
+    ),
+    resolved_at DATETIME,
+    UNIQUE (blog_name, new_href),
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
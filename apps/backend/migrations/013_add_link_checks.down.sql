@@ -0,0 +1,2 @@
+-- Remove link checks
+DROP TABLE IF EXISTS link_checks;
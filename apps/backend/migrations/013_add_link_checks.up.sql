@@ -0,0 +1,24 @@
+-- Outcome of the latest check of each link shown for a blog
+CREATE TABLE IF NOT EXISTS link_checks (
+    blog_name TEXT NOT NULL,
+    link_kind TEXT NOT NULL CHECK (link_kind IN ('blog', 'article', 'github')),
+    url TEXT NOT NULL,
+    checked_at DATETIME NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL CHECK (
+        status IN (
+            'ok',
+            'not_found',
+            'parked',
+            'soft_404',
+            'tls_expired',
+            'tls_invalid',
+            'error'
+        )
+    ),
+    detail TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (blog_name, link_kind),
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- Remove lifecycle states
+ALTER TABLE blog_cache DROP COLUMN last_new_article_at;
+ALTER TABLE blog_configs DROP COLUMN archived;
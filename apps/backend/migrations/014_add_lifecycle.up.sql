@@ -0,0 +1,9 @@
+-- Blogs kept on the site without being scraped, and when a blog last
+-- published an article never seen before, from which its lifecycle state is
+-- computed
+ALTER TABLE blog_configs ADD COLUMN archived BOOLEAN NOT NULL DEFAULT 0;
+ALTER TABLE blog_cache ADD COLUMN last_new_article_at DATETIME;
+
+-- The cache is updated when the latest article changes, the best estimate
+-- available for existing blogs
+UPDATE blog_cache SET last_new_article_at = updated_at;
@@ -0,0 +1,6 @@
+-- Remove blog metadata
+ALTER TABLE blog_cache DROP COLUMN feed_href;
+ALTER TABLE blog_cache DROP COLUMN favicon_href;
+ALTER TABLE blog_cache DROP COLUMN lang;
+ALTER TABLE blog_cache DROP COLUMN site_name;
+ALTER TABLE blog_cache DROP COLUMN description;
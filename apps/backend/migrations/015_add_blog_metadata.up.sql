@@ -0,0 +1,6 @@
+-- Site-level metadata read from the blog page
+ALTER TABLE blog_cache ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE blog_cache ADD COLUMN site_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE blog_cache ADD COLUMN lang TEXT NOT NULL DEFAULT '';
+ALTER TABLE blog_cache ADD COLUMN favicon_href TEXT NOT NULL DEFAULT '';
+ALTER TABLE blog_cache ADD COLUMN feed_href TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Remove blog icons
+DROP TABLE IF EXISTS blog_icons;
@@ -0,0 +1,12 @@
+-- Icon of each blog, downloaded from its favicon or GitHub avatar and
+-- normalized into a small PNG served by the API
+CREATE TABLE IF NOT EXISTS blog_icons (
+    blog_name TEXT PRIMARY KEY,
+    source_url TEXT NOT NULL,
+    content BLOB NOT NULL,
+    content_hash TEXT NOT NULL,
+    fetched_at DATETIME NOT NULL,
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
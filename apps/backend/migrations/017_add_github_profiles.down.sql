@@ -0,0 +1,2 @@
+-- Remove GitHub profiles
+DROP TABLE IF EXISTS github_profiles;
@@ -0,0 +1,14 @@
+-- GitHub profile of individual bloggers, fetched by the github enrichment job
+CREATE TABLE IF NOT EXISTS github_profiles (
+    blog_name TEXT PRIMARY KEY,
+    login TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    bio TEXT NOT NULL DEFAULT '',
+    followers INTEGER NOT NULL DEFAULT 0,
+    -- JSON array of the most starred repositories
+    top_repos TEXT NOT NULL DEFAULT '[]',
+    fetched_at DATETIME NOT NULL,
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- Blogs turned off by hand, neither scraped nor shown, which the blog
+-- directory file leaves alone
+ALTER TABLE blog_configs ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,20 @@
+-- Topics blogs are tagged with, e.g. databases or security, declared in the
+-- blog directory file
+CREATE TABLE IF NOT EXISTS tags (
+    name TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS blog_tags (
+    blog_name TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (blog_name, tag),
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE,
+    FOREIGN KEY (tag) REFERENCES tags (
+        name
+    ) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_blog_tags_tag ON blog_tags (tag);
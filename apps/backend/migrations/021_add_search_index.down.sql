@@ -0,0 +1,8 @@
+-- Remove the full-text index
+DROP TRIGGER IF EXISTS articles_search_delete;
+DROP TRIGGER IF EXISTS articles_search_update;
+DROP TRIGGER IF EXISTS articles_search_insert;
+DROP TRIGGER IF EXISTS blog_cache_search_delete;
+DROP TRIGGER IF EXISTS blog_cache_search_update;
+DROP TRIGGER IF EXISTS blog_cache_search_insert;
+DROP TABLE IF EXISTS search_index;
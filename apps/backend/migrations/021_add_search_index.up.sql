@@ -0,0 +1,55 @@
+-- Full-text index over blog names and descriptions and article titles,
+-- kept in sync by triggers. Blogs are stored under the negated id of their
+-- cache row, and articles under their own id.
+CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+    title,
+    body,
+    kind UNINDEXED,
+    blog_name UNINDEXED,
+    href UNINDEXED,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO search_index (rowid, title, body, kind, blog_name, href)
+SELECT -id, blog_name, description, 'blog', blog_name, blog_href
+FROM blog_cache;
+
+INSERT INTO search_index (rowid, title, body, kind, blog_name, href)
+SELECT id, article_name, '', 'article', blog_name, article_href
+FROM articles;
+
+CREATE TRIGGER IF NOT EXISTS blog_cache_search_insert
+AFTER INSERT ON blog_cache BEGIN
+    INSERT INTO search_index (rowid, title, body, kind, blog_name, href)
+    VALUES (-new.id, new.blog_name, new.description, 'blog', new.blog_name, new.blog_href);
+END;
+
+CREATE TRIGGER IF NOT EXISTS blog_cache_search_update
+AFTER UPDATE OF blog_name, blog_href, description ON blog_cache BEGIN
+    DELETE FROM search_index WHERE rowid = -old.id;
+    INSERT INTO search_index (rowid, title, body, kind, blog_name, href)
+    VALUES (-new.id, new.blog_name, new.description, 'blog', new.blog_name, new.blog_href);
+END;
+
+CREATE TRIGGER IF NOT EXISTS blog_cache_search_delete
+AFTER DELETE ON blog_cache BEGIN
+    DELETE FROM search_index WHERE rowid = -old.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS articles_search_insert
+AFTER INSERT ON articles BEGIN
+    INSERT INTO search_index (rowid, title, body, kind, blog_name, href)
+    VALUES (new.id, new.article_name, '', 'article', new.blog_name, new.article_href);
+END;
+
+CREATE TRIGGER IF NOT EXISTS articles_search_update
+AFTER UPDATE OF blog_name, article_name, article_href ON articles BEGIN
+    DELETE FROM search_index WHERE rowid = old.id;
+    INSERT INTO search_index (rowid, title, body, kind, blog_name, href)
+    VALUES (new.id, new.article_name, '', 'article', new.blog_name, new.article_href);
+END;
+
+CREATE TRIGGER IF NOT EXISTS articles_search_delete
+AFTER DELETE ON articles BEGIN
+    DELETE FROM search_index WHERE rowid = old.id;
+END;
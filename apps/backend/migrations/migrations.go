@@ -0,0 +1,28 @@
+// Package migrations embeds the SQL migrations so that the binaries can
+// apply them without the golang-migrate CLI.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var sqlite embed.FS
+
+//go:embed postgres/*.sql
+var postgres embed.FS
+
+// SQLite holds the migrations of the SQLite schema.
+var SQLite fs.FS = sqlite
+
+// Postgres holds the migrations of the PostgreSQL schema.
+var Postgres fs.FS = mustSub(postgres, "postgres")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,11 @@
+-- Drop every table, dependents first
+DROP TABLE IF EXISTS github_profiles;
+DROP TABLE IF EXISTS blog_icons;
+DROP TABLE IF EXISTS link_checks;
+DROP TABLE IF EXISTS blog_href_changes;
+DROP TABLE IF EXISTS shadow_results;
+DROP TABLE IF EXISTS page_snapshots;
+DROP TABLE IF EXISTS scrape_status;
+DROP TABLE IF EXISTS articles;
+DROP TABLE IF EXISTS blog_cache;
+DROP TABLE IF EXISTS blog_configs;
@@ -0,0 +1,169 @@
+-- PostgreSQL schema, equivalent to the SQLite migrations up to
+-- 017_add_github_profiles
+CREATE TABLE IF NOT EXISTS blog_configs (
+    id BIGSERIAL PRIMARY KEY,
+    blog_name TEXT NOT NULL UNIQUE,
+    blog_href TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('organization', 'individual')),
+    article_href_selector TEXT NOT NULL,
+    article_name_selector TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    github_href TEXT NOT NULL DEFAULT '',
+    next_page_selector TEXT NOT NULL DEFAULT '',
+    page_url_template TEXT NOT NULL DEFAULT '',
+    validation_rules TEXT NOT NULL DEFAULT '',
+    article_href_rule TEXT NOT NULL DEFAULT '',
+    article_name_rule TEXT NOT NULL DEFAULT '',
+    platform TEXT NOT NULL DEFAULT '',
+    extraction_script TEXT NOT NULL DEFAULT '',
+    shadow_config TEXT NOT NULL DEFAULT '',
+    archived BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS blog_cache (
+    id BIGSERIAL PRIMARY KEY,
+    blog_name TEXT NOT NULL UNIQUE,
+    blog_href TEXT NOT NULL,
+    latest_article_name TEXT,
+    latest_article_href TEXT,
+    kind TEXT NOT NULL CHECK (kind IN ('organization', 'individual')),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    github_href TEXT NOT NULL DEFAULT '',
+    last_new_article_at TIMESTAMPTZ,
+    description TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT '',
+    lang TEXT NOT NULL DEFAULT '',
+    favicon_href TEXT NOT NULL DEFAULT '',
+    feed_href TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS articles (
+    id BIGSERIAL PRIMARY KEY,
+    blog_name TEXT NOT NULL,
+    article_name TEXT NOT NULL,
+    article_href TEXT NOT NULL,
+    discovered_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (blog_name, article_href),
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS scrape_status (
+    blog_name TEXT PRIMARY KEY,
+    last_attempt_at TIMESTAMPTZ NOT NULL,
+    last_success_at TIMESTAMPTZ,
+    last_error TEXT NOT NULL DEFAULT '',
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    layout_fingerprint TEXT NOT NULL DEFAULT '',
+    layout_similarity DOUBLE PRECISION,
+    layout_changed_at TIMESTAMPTZ,
+    final_url TEXT NOT NULL DEFAULT '',
+    redirect_chain TEXT NOT NULL DEFAULT '',
+    permanent_redirect BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS page_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    blog_name TEXT NOT NULL,
+    page_url TEXT NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    content BYTEA NOT NULL,
+    matched_element TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_page_snapshots_blog
+ON page_snapshots (blog_name, fetched_at);
+
+CREATE TABLE IF NOT EXISTS shadow_results (
+    id BIGSERIAL PRIMARY KEY,
+    blog_name TEXT NOT NULL,
+    checked_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    live_name TEXT NOT NULL DEFAULT '',
+    live_href TEXT NOT NULL DEFAULT '',
+    live_error TEXT NOT NULL DEFAULT '',
+    shadow_name TEXT NOT NULL DEFAULT '',
+    shadow_href TEXT NOT NULL DEFAULT '',
+    shadow_error TEXT NOT NULL DEFAULT '',
+    agreed BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shadow_results_checked_at
+ON shadow_results (checked_at);
+
+CREATE TABLE IF NOT EXISTS blog_href_changes (
+    id BIGSERIAL PRIMARY KEY,
+    blog_name TEXT NOT NULL,
+    old_href TEXT NOT NULL,
+    new_href TEXT NOT NULL,
+    detected_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (
+        status IN ('pending', 'applied', 'rejected')
+    ),
+    resolved_at TIMESTAMPTZ,
+    UNIQUE (blog_name, new_href),
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS link_checks (
+    blog_name TEXT NOT NULL,
+    link_kind TEXT NOT NULL CHECK (link_kind IN ('blog', 'article', 'github')),
+    url TEXT NOT NULL,
+    checked_at TIMESTAMPTZ NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL CHECK (
+        status IN (
+            'ok',
+            'not_found',
+            'parked',
+            'soft_404',
+            'tls_expired',
+            'tls_invalid',
+            'error'
+        )
+    ),
+    detail TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (blog_name, link_kind),
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS blog_icons (
+    blog_name TEXT PRIMARY KEY,
+    source_url TEXT NOT NULL,
+    content BYTEA NOT NULL,
+    content_hash TEXT NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS github_profiles (
+    blog_name TEXT PRIMARY KEY,
+    login TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    bio TEXT NOT NULL DEFAULT '',
+    followers INTEGER NOT NULL DEFAULT 0,
+    -- JSON array of the most starred repositories
+    top_repos TEXT NOT NULL DEFAULT '[]',
+    fetched_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (blog_name) REFERENCES blog_configs (
+        blog_name
+    ) ON DELETE CASCADE
+);
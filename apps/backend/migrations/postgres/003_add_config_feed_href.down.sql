@@ -0,0 +1,2 @@
+-- Remove configured feeds
+ALTER TABLE blog_configs DROP COLUMN feed_href;
@@ -0,0 +1,2 @@
+-- Feed a blog is read from, set in the blog directory file
+ALTER TABLE blog_configs ADD COLUMN feed_href TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Remove disabled blogs
+ALTER TABLE blog_configs DROP COLUMN disabled;
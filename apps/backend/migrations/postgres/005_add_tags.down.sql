@@ -0,0 +1,3 @@
+-- Remove tags
+DROP TABLE IF EXISTS blog_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,3 @@
+-- Remove the full-text indexes
+DROP INDEX IF EXISTS idx_articles_search;
+DROP INDEX IF EXISTS idx_blog_cache_search;
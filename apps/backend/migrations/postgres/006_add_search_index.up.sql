@@ -0,0 +1,9 @@
+-- Full-text indexes over blog names and descriptions and article titles,
+-- which PostgreSQL keeps up to date by itself
+CREATE INDEX IF NOT EXISTS idx_blog_cache_search ON blog_cache USING GIN (
+    (setweight(to_tsvector('simple', blog_name), 'A') || setweight(to_tsvector('simple', description), 'D'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_articles_search ON articles USING GIN (
+    to_tsvector('simple', article_name)
+);